@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// githubApp is a GitHub App installation which the tool authenticates as.
+type githubApp struct {
+	appID          int64
+	installationID int64
+	privateKeyFile string
+}
+
+// installationToken mints an installation token of the app.
+// It authenticates as the app with a JWT signed by its private key, then exchanges it for the token.
+func (a githubApp) installationToken(ctx context.Context, baseURL string, httpClient *http.Client) (string, error) {
+	key, err := readPrivateKey(a.privateKeyFile)
+	if err != nil {
+		return "", err
+	}
+	jwt, err := appJWT(a.appID, key, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	client, err := newGithubClient(baseURL, authenticated(httpClient, jwt))
+	if err != nil {
+		return "", err
+	}
+	token, _, err := client.Apps.CreateInstallationToken(ctx, a.installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create an installation token. app-id: %d, installation-id: %d, detail: %w", a.appID, a.installationID, err)
+	}
+	return token.GetToken(), nil
+}
+
+// readPrivateKey reads the private key of the app in PEM. Both PKCS#1 (the format GitHub generates) and PKCS#8 are accepted.
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read private-key-file %q. detail: %w", path, err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("private-key-file %q is not PEM", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private-key-file %q. detail: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private-key-file %q is not a RSA key", path)
+	}
+	return key, nil
+}
+
+// appJWT returns a JWT which authenticates as the app.
+// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		// it is issued a bit in the past against clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		// 10 minutes at most
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign a JWT. detail: %w", err)
+	}
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
+
+// validate checks that all or none of the parameters are given. It reports whether they are given.
+func (a githubApp) validate() (bool, error) {
+	given := 0
+	for _, ok := range []bool{a.appID != 0, a.installationID != 0, a.privateKeyFile != ""} {
+		if ok {
+			given++
+		}
+	}
+	switch given {
+	case 0:
+		return false, nil
+	case 3:
+		return true, nil
+	default:
+		return false, errors.New("parameters app-id, installation-id, private-key-file are required together")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Values of -color
+const (
+	COLOR_AUTO   = "auto"
+	COLOR_ALWAYS = "always"
+	COLOR_NEVER  = "never"
+)
+
+// ANSI escape sequences
+const (
+	ANSI_RESET  = "\x1b[0m"
+	ANSI_BOLD   = "\x1b[1m"
+	ANSI_RED    = "\x1b[31m"
+	ANSI_GREEN  = "\x1b[32m"
+	ANSI_YELLOW = "\x1b[33m"
+	ANSI_CYAN   = "\x1b[36m"
+)
+
+// palette colorizes pieces of messages. The zero value prints them as is.
+type palette struct {
+	enabled bool
+}
+
+// newPalette returns the palette for the file. In the auto mode, colors are used only for a terminal unless NO_COLOR is set.
+// https://no-color.org/
+func newPalette(mode string, f *os.File) (palette, error) {
+	switch mode {
+	case COLOR_ALWAYS:
+		return palette{enabled: true}, nil
+	case COLOR_NEVER:
+		return palette{}, nil
+	case COLOR_AUTO:
+		return palette{enabled: os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(f)}, nil
+	default:
+		return palette{}, fmt.Errorf("invalid color %q. it must be one of %s, %s, %s", mode, COLOR_AUTO, COLOR_ALWAYS, COLOR_NEVER)
+	}
+}
+
+func (p palette) paint(code, s string) string {
+	if !p.enabled {
+		return s
+	}
+	return code + s + ANSI_RESET
+}
+
+func (p palette) name(s string) string { return p.paint(ANSI_BOLD+ANSI_CYAN, s) }
+func (p palette) size(s string) string { return p.paint(ANSI_GREEN, s) }
+func (p palette) warn(s string) string { return p.paint(ANSI_YELLOW, s) }
+func (p palette) err(s string) string  { return p.paint(ANSI_RED, s) }
+
+// colorWriter colorizes lines of the text log by their level.
+// A handler of slog writes a record in a single call, so each call is a line.
+type colorWriter struct {
+	w io.Writer
+	p palette
+}
+
+func (c colorWriter) Write(b []byte) (int, error) {
+	line := string(bytes.TrimSuffix(b, []byte("\n")))
+	switch {
+	case bytes.Contains(b, []byte("level=ERROR")):
+		line = c.p.err(line)
+	case bytes.Contains(b, []byte("level=WARN")):
+		line = c.p.warn(line)
+	default:
+		return c.w.Write(b)
+	}
+	if _, err := io.WriteString(c.w, line+"\n"); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
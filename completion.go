@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	COMMAND_NAME = "get-the-latest-artifact-on-github-action"
+
+	SHELL_BASH = "bash"
+	SHELL_ZSH  = "zsh"
+	SHELL_FISH = "fish"
+)
+
+// completionFlag describes a flag for completion scripts.
+type completionFlag struct {
+	name        string
+	description string
+	// takesValue is false for a boolean flag
+	takesValue bool
+}
+
+// completionFlags returns all flags of fs in lexical order.
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		isBool := false
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
+			isBool = b.IsBoolFlag()
+		}
+		flags = append(flags, completionFlag{
+			name:        f.Name,
+			description: firstSentence(f.Usage),
+			takesValue:  !isBool,
+		})
+	})
+	return flags
+}
+
+// firstSentence keeps descriptions short enough for a completion menu.
+func firstSentence(s string) string {
+	for i := 0; ; {
+		j := strings.Index(s[i:], ". ")
+		if j < 0 {
+			return strings.TrimSuffix(s, ".")
+		}
+		i += j
+		// an abbreviation doesn't end the sentence
+		if !strings.HasSuffix(s[:i], "e.g") && !strings.HasSuffix(s[:i], "i.e") {
+			return s[:i]
+		}
+		i += len(". ")
+	}
+}
+
+// writeCompletion writes the completion script for the shell.
+func writeCompletion(w io.Writer, shell string, fs *flag.FlagSet) error {
+	flags := completionFlags(fs)
+	switch shell {
+	case SHELL_BASH:
+		return writeBashCompletion(w, flags)
+	case SHELL_ZSH:
+		return writeZshCompletion(w, flags)
+	case SHELL_FISH:
+		return writeFishCompletion(w, flags)
+	default:
+		return fmt.Errorf("invalid completion %q. it must be one of %s, %s, %s", shell, SHELL_BASH, SHELL_ZSH, SHELL_FISH)
+	}
+}
+
+func writeBashCompletion(w io.Writer, flags []completionFlag) error {
+	var names, valueNames []string
+	for _, f := range flags {
+		names = append(names, "-"+f.name)
+		if f.takesValue {
+			valueNames = append(valueNames, "-"+f.name)
+		}
+	}
+	function := "_" + strings.ReplaceAll(COMMAND_NAME, "-", "_")
+	_, err := fmt.Fprintf(w, `%[1]s() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    # a value of the flag is completed as a file name
+    case " %[2]s " in
+        *" ${prev} "*) return ;;
+    esac
+    if [[ "${cur}" == -* ]]; then
+        COMPREPLY=($(compgen -W "%[3]s" -- "${cur}"))
+    fi
+}
+complete -o default -F %[1]s %[4]s
+`, function, strings.Join(valueNames, " "), strings.Join(names, " "), COMMAND_NAME)
+	return err
+}
+
+func writeZshCompletion(w io.Writer, flags []completionFlag) error {
+	// characters which have a meaning in a spec of _arguments
+	escape := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n_arguments \\\n", COMMAND_NAME)
+	for _, f := range flags {
+		spec := fmt.Sprintf("-%s[%s]", f.name, escape.Replace(f.description))
+		if f.takesValue {
+			spec += ":" + f.name + ":_files"
+		}
+		fmt.Fprintf(&b, "  '%s' \\\n", spec)
+	}
+	b.WriteString("  && return 0\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer, flags []completionFlag) error {
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	var b strings.Builder
+	for _, f := range flags {
+		// -o is an old style option which starts with a single dash
+		fmt.Fprintf(&b, "complete -c %s -o %s -d '%s'", COMMAND_NAME, f.name, escape.Replace(f.description))
+		if f.takesValue {
+			b.WriteString(" -r")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the file given by -config.
+// Its keys are the names of flags without "-", so that it mirrors them, e.g.
+//
+//	owner: niku
+//	repo: get-the-latest-artifact-on-github-action
+//	name: [linux, darwin]
+//	output-dir: ./out
+//	timeout: 15m
+//
+// The precedence is flags on the command line, the file, environment variables (e.g. GITHUB_REPOSITORY), then defaults.
+type Config struct {
+	// Values are the values of flags by their names. A repeatable flag like name can have multiple values.
+	Values map[string][]string
+}
+
+// loadConfig reads the YAML file.
+func loadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config %q. detail: %w", path, err)
+	}
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal(b, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to parse config %q. detail: %w", path, err)
+	}
+
+	c := &Config{Values: map[string][]string{}}
+	for key, node := range nodes {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			c.Values[key] = []string{node.Value}
+		case yaml.SequenceNode:
+			for _, n := range node.Content {
+				if n.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("invalid %s in config %q. its elements must be scalars", key, path)
+				}
+				c.Values[key] = append(c.Values[key], n.Value)
+			}
+		default:
+			return nil, fmt.Errorf("invalid %s in config %q. it must be a scalar or a list", key, path)
+		}
+	}
+	return c, nil
+}
+
+// apply sets the values to flags which aren't given on the command line, and returns names of the flags set by it.
+// fs.Visit visits them as well afterwards, so the names tell them from the ones on the command line.
+func (c *Config) apply(fs *flag.FlagSet) (map[string]bool, error) {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	applied := map[string]bool{}
+
+	// in a stable order for error messages
+	keys := make([]string, 0, len(c.Values))
+	for key := range c.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "config" {
+			return nil, errors.New("config can't be given in config")
+		}
+		if fs.Lookup(key) == nil {
+			return nil, fmt.Errorf("unknown key %q in config", key)
+		}
+		if explicit[key] {
+			continue
+		}
+		for _, v := range c.Values[key] {
+			if err := fs.Set(key, v); err != nil {
+				return nil, fmt.Errorf("invalid %s in config. detail: %w", key, err)
+			}
+		}
+		applied[key] = true
+	}
+	return applied, nil
+}
@@ -0,0 +1,231 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// It is enough to diagnose why the request failed.
+const MAX_ERROR_BODY_SNIPPET_BYTES = 512
+
+// errExpiredURL is returned when the storage denies the pre-signed url, which is usually because it has expired.
+var errExpiredURL = errors.New("the download url has expired or been denied")
+
+// Messages of the storage for a pre-signed url which has expired, e.g. of S3 or Azure Blob Storage.
+var expiredURLMarkers = []string{"AccessDenied", "Request has expired", "AuthenticationFailed", "Signature not valid"}
+
+// Download writes the zip archive of the artifact to w.
+func (d *Downloader) Download(ctx context.Context, owner, repo string, artifactID int64, w io.Writer) error {
+	return d.download(ctx, owner, repo, artifactID, w, 0)
+}
+
+// download writes the zip archive of the artifact to w. It fails once the archive exceeds maxSize bytes unless it is 0.
+func (d *Downloader) download(ctx context.Context, owner, repo string, artifactID int64, w io.Writer, maxSize int64) error {
+	// get an archive
+	// Only getting the response is retried. Once the body is being written to w, it can't be undone
+	// unless the rest of it is requested by Resume.
+	resp, body, err := d.openArchive(ctx, owner, repo, artifactID, 0)
+	if err != nil {
+		return err
+	}
+	total := resp.ContentLength
+	resumable := d.Resume && resp.Header.Get("Accept-Ranges") == "bytes"
+
+	if d.OnProgress != nil {
+		w = &progressWriter{w: w, total: total, onProgress: d.OnProgress}
+	}
+	var written int64
+	for resumes := 0; ; resumes++ {
+		recorder := &readErrorRecorder{r: body}
+		var r io.Reader = recorder
+		if maxSize > 0 {
+			// Content-Length may lie, so the body is capped as well. A byte over the cap tells that it is exceeded.
+			r = io.LimitReader(recorder, maxSize-written+1)
+		}
+		n, err := io.Copy(w, r)
+		resp.Body.Close()
+		written += n
+		if maxSize > 0 && written > maxSize {
+			return fmt.Errorf("the archive exceeds max-size %d bytes", maxSize)
+		}
+		if err == nil {
+			break
+		}
+		// only an interrupted body can be resumed, not a failure of w
+		if recorder.err == nil || !resumable || resumes >= d.Retries || ctx.Err() != nil {
+			return fmt.Errorf("unable to copy response body to file. detail: %w", err)
+		}
+		d.logger().Warn("resuming the download", "artifact_id", artifactID, "offset", written, "attempt", resumes+1, "retries", d.Retries, "error", err)
+		resp, body, err = d.openArchive(ctx, owner, repo, artifactID, written)
+		if err != nil {
+			return err
+		}
+	}
+	if total >= 0 && written != total {
+		return fmt.Errorf("size of the archive mismatched. expected: %d bytes, actual: %d bytes", total, written)
+	}
+	d.logger().Info("downloaded the archive", "owner", owner, "repo", repo, "artifact_id", artifactID, "bytes", written)
+	return nil
+}
+
+// openArchive gets a fresh url of the archive and returns the response from the offset and the reader of its body.
+// The url is pre-signed and may expire before it is used on a slow network, so it is requested again once in that case.
+func (d *Downloader) openArchive(ctx context.Context, owner, repo string, artifactID int64, offset int64) (*http.Response, io.Reader, error) {
+	for refreshed := false; ; refreshed = true {
+		resp, body, err := d.tryOpenArchive(ctx, owner, repo, artifactID, offset)
+		if err == nil || refreshed || !errors.Is(err, errExpiredURL) {
+			return resp, body, err
+		}
+		d.logger().Warn("requesting a fresh download url", "artifact_id", artifactID, "error", err)
+	}
+}
+
+func (d *Downloader) tryOpenArchive(ctx context.Context, owner, repo string, artifactID int64, offset int64) (*http.Response, io.Reader, error) {
+	downloadURL, err := d.DownloadURL(ctx, owner, repo, artifactID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp *http.Response
+	err = d.retry(ctx, "getting artifact", func() error {
+		var err error
+		resp, err = d.getArchive(ctx, downloadURL.String(), offset)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset > 0 {
+		return resp, resp.Body, nil
+	}
+	// An error page (e.g. of an expired url) may come with a successful status.
+	// It is told before writing anything, rather than failing to open the zip later.
+	body, err := checkZipMagic(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// checkZipMagic tells whether the body of resp starts with the signature of a zip archive.
+// The returned reader reads the whole body including the peeked bytes.
+func checkZipMagic(resp *http.Response) (io.Reader, error) {
+	br := bufio.NewReader(resp.Body)
+	magic, err := br.Peek(4)
+	// "PK\x05\x06" is the end of central directory which an empty archive starts with
+	if err == nil && (bytes.Equal(magic, []byte("PK\x03\x04")) || bytes.Equal(magic, []byte("PK\x05\x06"))) {
+		return br, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(br, MAX_ERROR_BODY_SNIPPET_BYTES))
+	err = fmt.Errorf("the downloaded archive is not a zip. the url may have expired or been denied. content-type: %q, body: %q", resp.Header.Get("Content-Type"), snippet)
+	if isExpiredURLBody(snippet) {
+		return nil, fmt.Errorf("%w. detail: %w", errExpiredURL, err)
+	}
+	return nil, err
+}
+
+// isExpiredURLBody reports whether the body of a response tells that the pre-signed url has expired.
+func isExpiredURLBody(body []byte) bool {
+	for _, marker := range expiredURLMarkers {
+		if bytes.Contains(body, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readErrorRecorder records the error of reading r except io.EOF.
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// DownloadURL returns the url of the archive of the artifact without downloading it.
+// The url is pre-signed and expires in a short time (about a minute), so it must be used promptly.
+func (d *Downloader) DownloadURL(ctx context.Context, owner, repo string, artifactID int64) (*url.URL, error) {
+	var downloadURL *url.URL
+	err := d.retry(ctx, "getting download url", func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		// The url is taken from the Location header of the redirect, so the archive isn't fetched here.
+		// followRedirects only follows a permanent redirect of a renamed repository.
+		downloadURL, resp, err = d.client.Actions.DownloadArtifact(ctx, owner, repo, artifactID, true)
+		d.observeRate("getting download url", resp)
+		return withStatus(resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get download url. detail: %w", err)
+	}
+	return downloadURL, nil
+}
+
+// getArchive returns the response whose body is the archive from the offset.
+func (d *Downloader) getArchive(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	// The url is pre-signed and has been obtained by the authenticated request.
+	// We must not send the token to the url because it points to an external storage.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to make a request for artifact. detail: %w", err)
+	}
+	expected := http.StatusOK
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		expected = http.StatusPartialContent
+	}
+	// The context also bounds reading the body.
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get artifact. detail: %w", err)
+	}
+	if resp.StatusCode != expected {
+		defer resp.Body.Close()
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, MAX_ERROR_BODY_SNIPPET_BYTES))
+		err := fmt.Errorf("unable to get artifact. status: %s, body: %s", resp.Status, snippet)
+		if resp.StatusCode == http.StatusForbidden || isExpiredURLBody(snippet) {
+			err = fmt.Errorf("%w. detail: %w", errExpiredURL, err)
+		}
+		return nil, &statusError{StatusCode: resp.StatusCode, err: err}
+	}
+	if offset > 0 {
+		// e.g. "bytes 1024-2047/2048"
+		var start int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unable to resume artifact from %d bytes. content-range: %q", offset, resp.Header.Get("Content-Range"))
+		}
+	}
+	return resp, nil
+}
+
+// progressWriter reports how many bytes have been written.
+type progressWriter struct {
+	w          io.Writer
+	written    int64
+	total      int64
+	onProgress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.onProgress(p.written, p.total)
+	return n, err
+}
@@ -0,0 +1,288 @@
+package downloader
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// https://docs.github.com/en/rest/guides/traversing-with-pagination#basics-of-pagination
+const MAX_NUMBER_PER_PAGE = 100
+
+// Keys for Options.SortBy
+const (
+	SORT_BY_CREATED = "created"
+	SORT_BY_UPDATED = "updated"
+	SORT_BY_SIZE    = "size"
+	SORT_BY_NAME    = "name"
+	// It sorts by the run number of the workflow run which produced the artifact.
+	// It is more deterministic than the creation time when runs finish out of order, but costs an API call per workflow run.
+	SORT_BY_RUN_NUMBER = "run-number"
+)
+
+// Orders for Options.Order
+const (
+	ORDER_ASC  = "asc"
+	ORDER_DESC = "desc"
+)
+
+// ErrNoArtifacts is returned when there are no artifacts which satisfy the conditions.
+var ErrNoArtifacts = errors.New("no artifacts found")
+
+// ErrRepositoryNotFound is returned when listing artifacts fails with 404 from the first page.
+// GitHub responds so for a repository which doesn't exist, and for a private one which the token can't read.
+var ErrRepositoryNotFound = errors.New("repository not found")
+
+// ListArtifacts returns all artifacts in the repository.
+func (d *Downloader) ListArtifacts(ctx context.Context, owner, repo string) ([]*github.Artifact, error) {
+	return d.listArtifacts(ctx, owner, repo, Options{})
+}
+
+// ListWorkflowRunArtifacts returns all artifacts produced by the workflow run.
+func (d *Downloader) ListWorkflowRunArtifacts(ctx context.Context, owner, repo string, runID int64) ([]*github.Artifact, error) {
+	return d.listWorkflowRunArtifacts(ctx, owner, repo, runID, Options{})
+}
+
+// listArtifacts lists artifacts in the repository within Options.MaxPages, Options.Since and Options.NewerThan.
+// Artifacts are filtered by Options.Name on the server, which saves pages in a repository with many artifacts.
+// Other filters like NameGlob and IgnoreCase are applied on the client.
+func (d *Downloader) listArtifacts(ctx context.Context, owner, repo string, opts Options) ([]*github.Artifact, error) {
+	var (
+		artifacts []*github.Artifact
+		err       error
+	)
+	if opts.Name != "" && !opts.IgnoreCase {
+		artifacts, err = d.listArtifactPages(ctx, fmt.Sprintf("artifacts named %q", opts.Name), opts, func(lo *github.ListOptions) (*github.ArtifactList, *github.Response, error) {
+			return d.listArtifactsByName(ctx, owner, repo, opts.Name, lo)
+		})
+	} else {
+		artifacts, err = d.listArtifactPages(ctx, "artifacts", opts, func(lo *github.ListOptions) (*github.ArtifactList, *github.Response, error) {
+			return d.client.Actions.ListArtifacts(ctx, owner, repo, lo)
+		})
+	}
+	// nothing has been listed only when the first page fails
+	if err != nil && len(artifacts) == 0 {
+		return nil, describeListError(err, fmt.Sprintf("repository %s/%s is", owner, repo), owner, repo)
+	}
+	return artifacts, err
+}
+
+// listArtifactsByName lists a page of artifacts whose name is exactly the same.
+// go-github v55 has no option for the name parameter of the API, so the request is built here.
+func (d *Downloader) listArtifactsByName(ctx context.Context, owner, repo, name string, lo *github.ListOptions) (*github.ArtifactList, *github.Response, error) {
+	query := url.Values{
+		"name":     {name},
+		"page":     {strconv.Itoa(lo.Page)},
+		"per_page": {strconv.Itoa(lo.PerPage)},
+	}
+	req, err := d.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%v/%v/actions/artifacts?%s", owner, repo, query.Encode()), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	artifactList := new(github.ArtifactList)
+	resp, err := d.client.Do(ctx, req, artifactList)
+	if err != nil {
+		return nil, resp, err
+	}
+	return artifactList, resp, nil
+}
+
+// listWorkflowRunArtifacts lists artifacts produced by the workflow run within Options.MaxPages, Options.Since and Options.NewerThan.
+func (d *Downloader) listWorkflowRunArtifacts(ctx context.Context, owner, repo string, runID int64, opts Options) ([]*github.Artifact, error) {
+	artifacts, err := d.listArtifactPages(ctx, fmt.Sprintf("artifacts of workflow run %d", runID), opts, func(lo *github.ListOptions) (*github.ArtifactList, *github.Response, error) {
+		return d.client.Actions.ListWorkflowRunArtifacts(ctx, owner, repo, runID, lo)
+	})
+	if err != nil && len(artifacts) == 0 {
+		return nil, describeListError(err, fmt.Sprintf("workflow run %d of %s/%s is", runID, owner, repo), owner, repo)
+	}
+	return artifacts, err
+}
+
+// describeListError tells whether the failure of the first page is likely a typo (404) or a lack of the permission (403).
+// They would be confusing as raw responses.
+func describeListError(err error, subject, owner, repo string) error {
+	// the rate limit responds 403 as well
+	var (
+		rateLimitErr  *github.RateLimitError
+		abuseLimitErr *github.AbuseRateLimitError
+	)
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseLimitErr) {
+		return err
+	}
+	switch statusCode(err) {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w. %s not found or not accessible with this token. check the owner and the repository for a typo. detail: %w", ErrRepositoryNotFound, subject, err)
+	case http.StatusForbidden:
+		return fmt.Errorf("the token is forbidden to read artifacts of %s/%s. it needs the read permission of actions, or the repo scope for a classic token. detail: %w", owner, repo, err)
+	default:
+		return err
+	}
+}
+
+func (d *Downloader) listArtifactPages(ctx context.Context, what string, opts Options, list func(lo *github.ListOptions) (*github.ArtifactList, *github.Response, error)) ([]*github.Artifact, error) {
+	// artifacts older than NewerThan aren't candidates either
+	cutoff := opts.Since
+	if opts.NewerThan.After(cutoff) {
+		cutoff = opts.NewerThan
+	}
+	var (
+		artifacts  []*github.Artifact
+		totalCount int64
+		pages      int
+		bounded    bool
+	)
+	err := d.paginate(ctx, what, opts.MaxPages, func(lo *github.ListOptions) (*github.Response, bool, error) {
+		artifactList, resp, err := list(lo)
+		if err != nil {
+			return resp, false, err
+		}
+		artifacts = append(artifacts, artifactList.Artifacts...)
+		totalCount = artifactList.GetTotalCount()
+		pages++
+		// Artifacts are listed newest first, so the rest of pages are older than the cutoff.
+		n := len(artifactList.Artifacts)
+		if !cutoff.IsZero() && n > 0 && artifactList.Artifacts[n-1].GetCreatedAt().Before(cutoff) {
+			bounded = true
+			return resp, true, nil
+		}
+		// Some proxies and GitHub Enterprise Server versions have omitted the next page of a full page.
+		// The total count tells whether there are more artifacts.
+		if resp.NextPage == 0 && n == lo.PerPage && int64(len(artifacts)) < totalCount {
+			d.logger().Debug("no next page for a full page, trying the following one", "what", what, "page", lo.Page, "total_count", totalCount)
+			resp.NextPage = lo.Page + 1
+		}
+		// an empty page never has a following one
+		return resp, n == 0, nil
+	})
+	if err != nil {
+		return artifacts, err
+	}
+	if opts.MaxPages > 0 && pages >= opts.MaxPages {
+		bounded = true
+	}
+	if !bounded && int64(len(artifacts)) != totalCount {
+		d.logger().Warn("the number of listed artifacts differs from the total count. artifacts may have been uploaded or deleted while listing", "what", what, "listed", len(artifacts), "total_count", totalCount)
+	}
+	return artifacts, nil
+}
+
+// FilterArtifacts returns artifacts which satisfy the options. They are sorted by Options.SortBy and Options.Order.
+func FilterArtifacts(artifacts []*github.Artifact, opts Options) ([]*github.Artifact, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// artifacts created after it are too young
+	youngest := time.Now().Add(-opts.MinAge)
+	var matched []*github.Artifact
+	for _, a := range artifacts {
+		if !opts.IncludeExpired && a.GetExpired() {
+			continue
+		}
+		if opts.missingIDs[a.GetID()] {
+			continue
+		}
+		// filter by name
+		if !opts.matchName(a.GetName()) {
+			continue
+		}
+		// filter by branch
+		if opts.Branch != "" && a.GetWorkflowRun().GetHeadBranch() != opts.Branch {
+			continue
+		}
+		// filter by commit
+		if opts.SHA != "" && !strings.HasPrefix(strings.ToLower(a.GetWorkflowRun().GetHeadSHA()), strings.ToLower(opts.SHA)) {
+			continue
+		}
+		// filter by creation time
+		if !opts.Since.IsZero() && a.GetCreatedAt().Before(opts.Since) {
+			continue
+		}
+		if !opts.Before.IsZero() && !a.GetCreatedAt().Before(opts.Before) {
+			continue
+		}
+		if !opts.NewerThan.IsZero() && !a.GetCreatedAt().After(opts.NewerThan) {
+			continue
+		}
+		if opts.MinAge > 0 && a.GetCreatedAt().After(youngest) {
+			continue
+		}
+		if !opts.matchFilters(a) {
+			continue
+		}
+		matched = append(matched, a)
+	}
+
+	sortArtifacts(matched, opts.SortBy, opts.Order)
+	return matched, nil
+}
+
+// sortArtifacts sorts artifacts by the key in the order. They are sorted by createdAt desc by default.
+// Ties are broken by the ID which increases monotonically, so that the selection is deterministic
+// even if artifacts are created at the same second.
+func sortArtifacts(artifacts []*github.Artifact, sortBy, order string) {
+	var compare func(a, b *github.Artifact) int
+	switch sortBy {
+	case SORT_BY_UPDATED:
+		compare = func(a, b *github.Artifact) int { return a.GetUpdatedAt().Compare(b.GetUpdatedAt().Time) }
+	case SORT_BY_SIZE:
+		compare = func(a, b *github.Artifact) int { return cmp.Compare(a.GetSizeInBytes(), b.GetSizeInBytes()) }
+	case SORT_BY_NAME:
+		compare = func(a, b *github.Artifact) int { return strings.Compare(a.GetName(), b.GetName()) }
+	case SORT_BY_RUN_NUMBER:
+		// Workflow runs are needed, so the Downloader sorts them beforehand.
+		return
+	default:
+		compare = func(a, b *github.Artifact) int { return a.GetCreatedAt().Compare(b.GetCreatedAt().Time) }
+	}
+	less := func(a, b *github.Artifact) bool {
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		return a.GetID() < b.GetID()
+	}
+	if order == ORDER_ASC {
+		sort.SliceStable(artifacts, func(i, j int) bool { return less(artifacts[i], artifacts[j]) })
+	} else {
+		sort.SliceStable(artifacts, func(i, j int) bool { return less(artifacts[j], artifacts[i]) })
+	}
+}
+
+// SelectArtifact returns the first artifact which satisfies the options, that is the newest one by default.
+// Options.Offset selects the following one.
+// It returns an error wrapping ErrNoArtifacts when there are no such artifacts.
+func SelectArtifact(artifacts []*github.Artifact, opts Options) (*github.Artifact, error) {
+	matched, err := FilterArtifacts(artifacts, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(matched) == 0 {
+		err := ErrNoArtifacts
+		if conditions := opts.conditions(); len(conditions) > 0 {
+			err = fmt.Errorf("%w with %s", err, strings.Join(conditions, ", "))
+		}
+		if !opts.IncludeExpired {
+			opts.IncludeExpired = true
+			// the options have been validated above
+			if expired, _ := FilterArtifacts(artifacts, opts); len(expired) > 0 {
+				err = fmt.Errorf("%w (all %d artifacts are expired)", err, len(expired))
+			}
+		}
+		return nil, err
+	}
+	if opts.Offset >= len(matched) {
+		return nil, fmt.Errorf("%w at offset %d. only %d artifacts available", ErrNoArtifacts, opts.Offset, len(matched))
+	}
+
+	// get the newest artifact, or older one by the offset
+	return matched[opts.Offset], nil
+}
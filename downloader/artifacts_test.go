@@ -0,0 +1,37 @@
+package downloader
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+func TestSelectArtifactBreaksTies(t *testing.T) {
+	created := &github.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var artifacts []*github.Artifact
+	for _, id := range []int64{5, 3, 9, 1, 7} {
+		artifacts = append(artifacts, &github.Artifact{ID: github.Int64(id), Name: github.String("build"), CreatedAt: created})
+	}
+
+	for _, tt := range []struct {
+		order string
+		want  int64
+	}{
+		{order: ORDER_DESC, want: 9},
+		{order: ORDER_ASC, want: 1},
+	} {
+		// the order of the API response must not matter
+		for i := 0; i < 20; i++ {
+			rand.Shuffle(len(artifacts), func(i, j int) { artifacts[i], artifacts[j] = artifacts[j], artifacts[i] })
+			artifact, err := SelectArtifact(artifacts, Options{Order: tt.order})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if artifact.GetID() != tt.want {
+				t.Fatalf("selected artifact %d in %s order of %v, want %d", artifact.GetID(), tt.order, artifactIDs(artifacts), tt.want)
+			}
+		}
+	}
+}
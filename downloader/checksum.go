@@ -0,0 +1,31 @@
+package downloader
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// checksum is the digest of an extracted file.
+type checksum struct {
+	// name is the path relative to the output directory
+	name   string
+	digest []byte
+}
+
+// writeChecksumFile writes checksums into the path in the format of sha256sum, so that `sha256sum -c` verifies them
+// in the output directory.
+func writeChecksumFile(path string, checksums []checksum) error {
+	sort.Slice(checksums, func(i, j int) bool { return checksums[i].name < checksums[j].name })
+	var b strings.Builder
+	for _, c := range checksums {
+		name := c.name
+		// sha256sum escapes them and marks the line with a leading backslash
+		if strings.ContainsAny(name, "\\\n\r") {
+			name = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(name)
+			b.WriteString(`\`)
+		}
+		b.WriteString(hex.EncodeToString(c.digest) + "  " + name + "\n")
+	}
+	return writeFileAtomically(path, "checksum file", []byte(b.String()))
+}
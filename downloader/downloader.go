@@ -0,0 +1,1187 @@
+// Package downloader provides getting the latest artifact generated by github action in specific repository.
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// DEFAULT_STREAM_MAX_BYTES is the default of Options.StreamMaxBytes.
+const DEFAULT_STREAM_MAX_BYTES = 256 << 20
+
+// MAX_MISSING_FALLBACKS bounds how many times Options.FallbackOnMissing selects the next artifact.
+const MAX_MISSING_FALLBACKS = 3
+
+// Options controls which artifact is selected and where it is extracted.
+type Options struct {
+	// Name selects artifacts whose name is exactly the same. All artifacts are candidates if empty.
+	Name string
+	// NameGlob selects artifacts whose name matches the pattern. See path.Match for the syntax.
+	NameGlob string
+	// ExcludeNames removes artifacts whose name matches any of the patterns. See path.Match for the syntax.
+	// It is applied after Name and NameGlob, so an artifact is a candidate only if it is included and not excluded.
+	ExcludeNames []string
+	// IgnoreCase makes Name, NameGlob and ExcludeNames case-insensitive.
+	IgnoreCase bool
+	// RunID narrows candidates down to artifacts produced by the workflow run.
+	// It is cheaper than listing all artifacts in the repository.
+	RunID int64
+	// RunAttempt narrows candidates down to artifacts produced by the attempt of the workflow run of RunID. 0 means all attempts.
+	// Artifacts don't tell their attempt, so the ones created while the attempt ran, until the next attempt starts, are selected.
+	RunAttempt int
+	// Workflow selects artifacts produced by runs of the workflow. It is a file name (e.g. release.yml) or a numeric workflow ID.
+	// It costs API calls to list runs of the workflow, from the newest until the runs of the listed artifacts are found, within MaxPages.
+	Workflow string
+	// Branch selects artifacts produced by workflow runs on the branch.
+	Branch string
+	// SHA selects artifacts produced by workflow runs for the commit. An abbreviated prefix is allowed.
+	SHA string
+	// IncludeExpired makes expired artifacts candidates. They are skipped by default because they can't be downloaded.
+	IncludeExpired bool
+	// SortBy is the key to sort candidates. One of SORT_BY_*. SORT_BY_CREATED is used if empty.
+	// Ties are broken by the artifact ID in the same order.
+	// FilterArtifacts and SelectArtifact keep the given order for SORT_BY_RUN_NUMBER. The Downloader sorts them beforehand.
+	SortBy string
+	// Order is the order to sort candidates. ORDER_ASC or ORDER_DESC. ORDER_DESC is used if empty.
+	// The first one is selected, so it is the newest one by default.
+	Order string
+	// Offset selects the Nth artifact after sorting instead of the first one. 0 means the first.
+	Offset int
+	// MaxPages stops listing artifacts after the number of pages (100 artifacts per page). 0 means no limit.
+	MaxPages int
+	// Since selects artifacts created at or after it. Listing artifacts also stops once they are older than it.
+	// The zero value means no limit.
+	Since time.Time
+	// Before selects artifacts created strictly before it, e.g. to reproduce the latest artifact as of the time.
+	// It composes a window with Since. The zero value means no limit.
+	Before time.Time
+	// NewerThan selects artifacts created after it. The zero value means no limit.
+	NewerThan time.Time
+	// MinAge skips artifacts created within the duration, which may still be being uploaded. 0 means no limit.
+	// It is relative to the time of filtering, so it is evaluated again on each poll of WaitTimeout.
+	MinAge time.Duration
+	// Filters are predicates which candidates must satisfy all of, made by ParseFilter.
+	// They generalize the conditions above, e.g. a size range. Expired artifacts are candidates only with IncludeExpired anyway.
+	Filters []Filter
+	// WaitTimeout keeps listing artifacts every POLL_INTERVAL until one satisfies the options, up to the duration.
+	// It is useful to wait for an artifact which hasn't been uploaded yet, with NewerThan. 0 means no wait.
+	WaitTimeout time.Duration
+	// OutputDir is the directory which files in the artifact are extracted into.
+	// The current directory is used if empty.
+	OutputDir string
+	// FS is where files are extracted instead of OutputDir if set, e.g. MemFS to extract in memory.
+	// Symlinks are skipped with a warning, and Overwrite must be OVERWRITE_ALWAYS since existing files can't be told.
+	// OutputDir is still where the archive is saved by KeepZip and NoExtract, and isn't made otherwise. Manifest can't be used with it.
+	FS WriterFS
+	// MaxSize refuses to download an archive larger than the bytes. 0 means no limit.
+	// The download is aborted as well once it exceeds the size, even if the size of the artifact tells otherwise.
+	MaxSize int64
+	// ArchiveSHA256 is the expected SHA-256 digest of the archive in hex. It is verified before extraction if set.
+	ArchiveSHA256 string
+	// ExtractMatch only extracts files whose name in the archive matches the pattern. See path.Match for the syntax.
+	ExtractMatch string
+	// ExtractExclude doesn't extract files whose name in the archive matches the pattern. It wins over ExtractMatch.
+	ExtractExclude string
+	// Overwrite is the policy for files which already exist. One of OVERWRITE_*. OVERWRITE_ALWAYS is used if empty.
+	Overwrite string
+	// ExtractConcurrency is the number of workers extracting files. runtime.GOMAXPROCS(0) is used if it is 0.
+	ExtractConcurrency int
+	// SkipUnsafeSymlinks skips symlinks pointing outside of OutputDir, and entries under symlinks in the archive, with a warning instead of failing.
+	SkipUnsafeSymlinks bool
+	// FailOnEmptyExtract fails with ErrEmptyArchive if the archive has no files, or none of them satisfies ExtractMatch and ExtractExclude.
+	// An empty artifact usually means that the workflow producing it is broken.
+	FailOnEmptyExtract bool
+	// Flatten extracts all files into OutputDir itself by their base names, dropping directories in the archive.
+	Flatten bool
+	// FlattenCollision is the policy for files with the same base name when flattened. One of FLATTEN_COLLISION_*.
+	// FLATTEN_COLLISION_ERROR is used if empty. FLATTEN_COLLISION_SUFFIX numbers the later ones in the order of the archive.
+	FlattenCollision string
+	// StripComponents removes the number of leading directories from names in the archive like tar --strip-components,
+	// e.g. "myapp-1.2.3/bin/run" is extracted as "bin/run" with 1. Entries without enough directories are skipped.
+	// ExtractMatch and ExtractExclude apply to the stripped names.
+	StripComponents int
+	// Prefix is a relative directory joined in front of every name in the archive when it is written, e.g. to merge artifacts
+	// into the same OutputDir without collisions. It applies after StripComponents and Flatten. It must not contain "..".
+	Prefix string
+	// KeepZip saves the downloaded archive at ZipPath instead of removing it after extraction.
+	KeepZip bool
+	// NoExtract only saves the archive at ZipPath without extracting it. It implies KeepZip.
+	NoExtract bool
+	// ZipPath is where the archive is saved. <OutputDir>/<artifact name>.zip is used if empty.
+	ZipPath string
+	// Manifest is the path of a JSON file listing absolute paths and sizes of extracted files. It is written after extraction if set.
+	Manifest string
+	// ChecksumFile is the path of a file listing SHA-256 digests of extracted regular files in the format of sha256sum.
+	// Paths in it are relative to OutputDir. It is written after extraction if set.
+	ChecksumFile string
+	// FallbackOnMissing selects the next artifact when the selected one has been deleted or expired before it is downloaded,
+	// up to MAX_MISSING_FALLBACKS times. It applies to LatestArtifact.
+	FallbackOnMissing bool
+	// LastID is the ID of the artifact downloaded last time. LatestArtifact returns ErrUnchanged without downloading anything
+	// if the selected artifact has the ID. 0 means none.
+	LastID int64
+	// ReleaseFallback downloads an asset of the latest release instead when there are no artifacts satisfying the options.
+	// Assets are selected by Name and NameGlob as well. A zip asset is extracted, and others are saved into FS or OutputDir. See LatestReleaseAsset.
+	ReleaseFallback bool
+	// DeleteAfterDownload deletes the artifact after it is downloaded and extracted successfully.
+	// The token needs the write permission of actions.
+	DeleteAfterDownload bool
+	// Stream extracts the archive in memory without a temp file if it is not larger than StreamMaxBytes.
+	// It saves the disk and the time to write the archive at the cost of memory as large as the archive.
+	// It is ignored when the archive is kept.
+	Stream bool
+	// StreamMaxBytes is the largest archive extracted in memory by Stream. DEFAULT_STREAM_MAX_BYTES is used if it is 0.
+	StreamMaxBytes int64
+	// TempDir is the directory where the archive is temporarily saved unless it is kept. os.TempDir() is used if empty.
+	// Point it to a large volume when the default one (e.g. tmpfs) can't hold the archive.
+	TempDir string
+
+	// missingIDs are artifacts which have been found missing by FallbackOnMissing. They are not candidates.
+	missingIDs map[int64]bool
+}
+
+func (o Options) validate() error {
+	if o.NameGlob != "" {
+		if _, err := path.Match(o.NameGlob, ""); err != nil {
+			return fmt.Errorf("invalid name-glob pattern %q. detail: %w", o.NameGlob, err)
+		}
+	}
+	if _, err := path.Match(o.ExtractMatch, ""); err != nil {
+		return fmt.Errorf("invalid extract-match pattern %q. detail: %w", o.ExtractMatch, err)
+	}
+	for _, pattern := range o.ExcludeNames {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude-name pattern %q. detail: %w", pattern, err)
+		}
+	}
+	if _, err := path.Match(o.ExtractExclude, ""); err != nil {
+		return fmt.Errorf("invalid extract-exclude pattern %q. detail: %w", o.ExtractExclude, err)
+	}
+	if strings.Trim(o.SHA, "0123456789abcdefABCDEF") != "" {
+		return fmt.Errorf("invalid sha %q. it must be hexadecimal", o.SHA)
+	}
+	if o.ArchiveSHA256 != "" {
+		if b, err := hex.DecodeString(o.ArchiveSHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("invalid sha256 %q. it must be 64 hexadecimal characters", o.ArchiveSHA256)
+		}
+	}
+	if o.RunAttempt < 0 {
+		return fmt.Errorf("invalid run-attempt %d. it must not be negative", o.RunAttempt)
+	}
+	if o.RunAttempt != 0 && o.RunID == 0 {
+		return errors.New("run-attempt needs run-id")
+	}
+	if o.MaxPages < 0 {
+		return fmt.Errorf("invalid max-pages %d. it must not be negative", o.MaxPages)
+	}
+	switch o.SortBy {
+	case "", SORT_BY_CREATED, SORT_BY_UPDATED, SORT_BY_SIZE, SORT_BY_NAME, SORT_BY_RUN_NUMBER:
+	default:
+		return fmt.Errorf("invalid sort-by %q. it must be one of %s, %s, %s, %s, %s", o.SortBy, SORT_BY_CREATED, SORT_BY_UPDATED, SORT_BY_SIZE, SORT_BY_NAME, SORT_BY_RUN_NUMBER)
+	}
+	switch o.Order {
+	case "", ORDER_ASC, ORDER_DESC:
+	default:
+		return fmt.Errorf("invalid order %q. it must be %s or %s", o.Order, ORDER_ASC, ORDER_DESC)
+	}
+	switch o.Overwrite {
+	case "", OVERWRITE_ALWAYS, OVERWRITE_NEVER, OVERWRITE_ERROR:
+	default:
+		return fmt.Errorf("invalid overwrite %q. it must be one of %s, %s, %s", o.Overwrite, OVERWRITE_ALWAYS, OVERWRITE_NEVER, OVERWRITE_ERROR)
+	}
+	if o.FS != nil && o.Overwrite != "" && o.Overwrite != OVERWRITE_ALWAYS {
+		return fmt.Errorf("overwrite %q can't be used with FS. existing files in it can't be told", o.Overwrite)
+	}
+	if o.FS != nil && o.Manifest != "" {
+		return errors.New("manifest can't be used with FS. files in it have no paths on the disk")
+	}
+	switch o.FlattenCollision {
+	case "", FLATTEN_COLLISION_ERROR, FLATTEN_COLLISION_SUFFIX:
+	default:
+		return fmt.Errorf("invalid flatten-collision %q. it must be %s or %s", o.FlattenCollision, FLATTEN_COLLISION_ERROR, FLATTEN_COLLISION_SUFFIX)
+	}
+	for _, f := range o.Filters {
+		// the zero value isn't made by ParseFilter
+		if f.key == "" {
+			return errors.New("invalid filter. make it by ParseFilter")
+		}
+	}
+	if err := validatePrefix(o.Prefix); err != nil {
+		return err
+	}
+	if o.StripComponents < 0 {
+		return fmt.Errorf("invalid strip-components %d. it must not be negative", o.StripComponents)
+	}
+	if o.ExtractConcurrency < 0 {
+		return fmt.Errorf("invalid extract-concurrency %d. it must not be negative", o.ExtractConcurrency)
+	}
+	if o.Offset < 0 {
+		return fmt.Errorf("invalid offset %d. it must not be negative", o.Offset)
+	}
+	if !o.Since.IsZero() && !o.Before.IsZero() && !o.Since.Before(o.Before) {
+		return fmt.Errorf("invalid window. since %s must be before %s", o.Since.Format(time.RFC3339), o.Before.Format(time.RFC3339))
+	}
+	if o.MaxSize < 0 {
+		return fmt.Errorf("invalid max-size %d. it must not be negative", o.MaxSize)
+	}
+	if o.StreamMaxBytes < 0 {
+		return fmt.Errorf("invalid stream-max-bytes %d. it must not be negative", o.StreamMaxBytes)
+	}
+	if o.MinAge < 0 {
+		return fmt.Errorf("invalid min-age %s. it must not be negative", o.MinAge)
+	}
+	if o.WaitTimeout < 0 {
+		return fmt.Errorf("invalid wait-timeout %s. it must not be negative", o.WaitTimeout)
+	}
+	return nil
+}
+
+// conditions describes the filters for messages.
+func (o Options) conditions() []string {
+	var conditions []string
+	if o.Name != "" {
+		conditions = append(conditions, fmt.Sprintf("name %q", o.Name))
+	}
+	if o.NameGlob != "" {
+		conditions = append(conditions, fmt.Sprintf("name matching %q", o.NameGlob))
+	}
+	for _, pattern := range o.ExcludeNames {
+		conditions = append(conditions, fmt.Sprintf("name not matching %q", pattern))
+	}
+	if o.IgnoreCase && (o.Name != "" || o.NameGlob != "" || len(o.ExcludeNames) > 0) {
+		conditions = append(conditions, "ignoring case")
+	}
+	if o.RunID != 0 {
+		conditions = append(conditions, fmt.Sprintf("workflow run %d", o.RunID))
+	}
+	if o.RunAttempt != 0 {
+		conditions = append(conditions, fmt.Sprintf("attempt %d", o.RunAttempt))
+	}
+	if o.Workflow != "" {
+		conditions = append(conditions, fmt.Sprintf("workflow %q", o.Workflow))
+	}
+	if o.Branch != "" {
+		conditions = append(conditions, fmt.Sprintf("branch %q", o.Branch))
+	}
+	if o.SHA != "" {
+		conditions = append(conditions, fmt.Sprintf("sha %q", o.SHA))
+	}
+	if !o.Since.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("created since %s", o.Since.Format(time.RFC3339)))
+	}
+	if !o.Before.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("created before %s", o.Before.Format(time.RFC3339)))
+	}
+	if !o.NewerThan.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("created after %s", o.NewerThan.Format(time.RFC3339)))
+	}
+	if o.MinAge > 0 {
+		conditions = append(conditions, fmt.Sprintf("at least %s old", o.MinAge))
+	}
+	for _, f := range o.Filters {
+		conditions = append(conditions, fmt.Sprintf("filter %q", f))
+	}
+	if len(o.missingIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("except %d missing artifacts", len(o.missingIDs)))
+	}
+	return conditions
+}
+
+// matchFilters reports whether the artifact satisfies all of Filters.
+func (o Options) matchFilters(a *github.Artifact) bool {
+	for _, f := range o.Filters {
+		if !f.Match(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchName reports whether the name satisfies Name and NameGlob, and is not excluded by ExcludeNames.
+func (o Options) matchName(name string) bool {
+	want, pattern := o.Name, o.NameGlob
+	if o.IgnoreCase {
+		want, pattern, name = strings.ToLower(want), strings.ToLower(pattern), strings.ToLower(name)
+	}
+	if want != "" && name != want {
+		return false
+	}
+	if pattern != "" {
+		// the pattern has been validated beforehand
+		if ok, _ := path.Match(pattern, name); !ok {
+			return false
+		}
+	}
+	for _, exclude := range o.ExcludeNames {
+		if o.IgnoreCase {
+			exclude = strings.ToLower(exclude)
+		}
+		if excluded, _ := path.Match(exclude, name); excluded {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldExtract reports whether the file is extracted according to ExtractMatch and ExtractExclude.
+func (o Options) shouldExtract(name string) bool {
+	// the patterns have been validated beforehand
+	if o.ExtractExclude != "" {
+		if excluded, _ := path.Match(o.ExtractExclude, name); excluded {
+			return false
+		}
+	}
+	if o.ExtractMatch != "" {
+		matched, _ := path.Match(o.ExtractMatch, name)
+		return matched
+	}
+	return true
+}
+
+func (o Options) extractConcurrency() int {
+	if o.ExtractConcurrency == 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.ExtractConcurrency
+}
+
+func (o Options) streamMaxBytes() int64 {
+	if o.StreamMaxBytes == 0 {
+		return DEFAULT_STREAM_MAX_BYTES
+	}
+	return o.StreamMaxBytes
+}
+
+func (o Options) outputDir() string {
+	if o.OutputDir == "" {
+		return "."
+	}
+	return o.OutputDir
+}
+
+// prepareOutput creates OutputDir unless nothing is written there, that is files are extracted into FS and the archive isn't kept.
+func (o Options) prepareOutput() error {
+	if o.FS != nil && !o.KeepZip && !o.NoExtract {
+		return nil
+	}
+	return prepareOutputDir(o.outputDir())
+}
+
+func (o Options) zipPath(artifact *github.Artifact) string {
+	if o.ZipPath == "" {
+		return filepath.Join(o.outputDir(), artifact.GetName()+".zip")
+	}
+	return o.ZipPath
+}
+
+// Downloader gets artifacts through the GitHub API.
+type Downloader struct {
+	// Retries is the number of retries for transient failures of API calls and the archive download.
+	Retries int
+	// RetryBackoff is the interval before the first retry, which is doubled on each retry up to MaxRetryBackoff.
+	// Each sleep is randomized between 0 and the interval. INITIAL_RETRY_BACKOFF and MAX_RETRY_BACKOFF are used if they are 0.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// Resume requests the rest of the archive when the download is interrupted, up to Retries times.
+	// It needs the server to support range requests, which the storage of artifacts does.
+	Resume bool
+	// WaitForRateLimit makes listing artifacts sleep until the rate limit resets instead of failing.
+	// It fails anyway when the limit resets after the deadline of the context.
+	WaitForRateLimit bool
+	// PerPage is the number of items requested per page of listings. It is clamped to 1..MAX_NUMBER_PER_PAGE.
+	// MAX_NUMBER_PER_PAGE is used if it is 0. A smaller one is useful to exercise pagination, but costs more API calls.
+	PerPage int
+	// OnSelect is called with the selected artifact before downloading it if set.
+	// It may be called again for the next one when the selected one is missing. See Options.FallbackOnMissing.
+	OnSelect func(artifact *github.Artifact)
+	// OnDownload is called with the artifact after it is downloaded and its files are extracted or written successfully if set.
+	OnDownload func(artifact *github.Artifact)
+	// OnDelete is called with the artifact after it is deleted by Options.DeleteAfterDownload if set.
+	OnDelete func(artifact *github.Artifact)
+	// OnFile is called with the name in the archive and the size of each file after it is extracted if set.
+	// Calls are serialized even though files are extracted concurrently.
+	OnFile func(name string, size int64)
+	// OnProgress is called while the archive is being downloaded if set.
+	// total is -1 when the size is unknown.
+	OnProgress func(written, total int64)
+	// Logger receives diagnostic messages with attributes like owner, repo, artifact_id and bytes if set.
+	// Each API page fetched and the rate limit told by each API response are logged at the debug level. See OnFile to observe each file extracted.
+	Logger *slog.Logger
+
+	// HTTPClient is used to get an archive from the pre-signed url. The token must not be sent with it.
+	// Give a client with the same transport as the one of the GitHub client, so that settings like the proxy apply to both.
+	// http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+
+	client *github.Client
+
+	onFileMu sync.Mutex
+
+	rateMu    sync.Mutex
+	rateUsage RateUsage
+
+	runsMu sync.Mutex
+	// runs caches workflow runs by their ID.
+	runs map[int64]*github.WorkflowRun
+}
+
+// New returns a Downloader which uses the given client to call the GitHub API.
+func New(client *github.Client) *Downloader {
+	return &Downloader{
+		client:     client,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+func (d *Downloader) onFile(name string, size int64) {
+	if d.OnFile == nil {
+		return
+	}
+	d.onFileMu.Lock()
+	defer d.onFileMu.Unlock()
+	d.OnFile(name, size)
+}
+
+func (d *Downloader) onDownload(artifact *github.Artifact) {
+	if d.OnDownload != nil {
+		d.OnDownload(artifact)
+	}
+}
+
+func (d *Downloader) perPage() int {
+	if d.PerPage == 0 {
+		return MAX_NUMBER_PER_PAGE
+	}
+	return min(max(d.PerPage, 1), MAX_NUMBER_PER_PAGE)
+}
+
+func (d *Downloader) httpClient() *http.Client {
+	if d.HTTPClient != nil {
+		return d.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// discardLogger is used when Logger isn't set. No levels are enabled for it.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+
+func (d *Downloader) logger() *slog.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return discardLogger
+}
+
+// LatestArtifact downloads the latest artifact in the repository and extracts it.
+// It returns the directory which the artifact is extracted into, or the path of the archive if NoExtract is set.
+func (d *Downloader) LatestArtifact(ctx context.Context, owner, repo string, opts Options) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	if err := opts.prepareOutput(); err != nil {
+		return "", err
+	}
+
+	artifact, err := d.selectLatest(ctx, owner, repo, opts)
+	if errors.Is(err, ErrNoArtifacts) && opts.ReleaseFallback {
+		d.logger().Info("falling back to the latest release", "owner", owner, "repo", repo, "detail", err.Error())
+		path, releaseErr := d.LatestReleaseAsset(ctx, owner, repo, opts)
+		if releaseErr != nil {
+			return "", fmt.Errorf("%w. release fallback also failed. detail: %v", err, releaseErr)
+		}
+		return path, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := checkUnchanged(artifact, opts); err != nil {
+		return "", err
+	}
+	path, err := d.fetch(ctx, owner, repo, artifact, opts)
+	// The artifact may have been deleted or expired since it was listed. Nothing has been extracted in that case.
+	for fallbacks := 0; opts.FallbackOnMissing && isNotFound(err) && fallbacks < MAX_MISSING_FALLBACKS; fallbacks++ {
+		d.logger().Warn("the selected artifact is missing. selecting the next one", "artifact_id", artifact.GetID(), "name", artifact.GetName(), "error", err)
+		if opts.missingIDs == nil {
+			opts.missingIDs = map[int64]bool{}
+		}
+		opts.missingIDs[artifact.GetID()] = true
+		artifact, err = d.selectLatest(ctx, owner, repo, opts)
+		if err != nil {
+			return "", err
+		}
+		if err := checkUnchanged(artifact, opts); err != nil {
+			return "", err
+		}
+		path, err = d.fetch(ctx, owner, repo, artifact, opts)
+	}
+	return path, err
+}
+
+// ErrUnchanged is returned when the selected artifact is the one of Options.LastID.
+var ErrUnchanged = errors.New("the artifact is unchanged since the last download")
+
+func checkUnchanged(artifact *github.Artifact, opts Options) error {
+	if opts.LastID == 0 || artifact.GetID() != opts.LastID {
+		return nil
+	}
+	return fmt.Errorf("%w. id: %d", ErrUnchanged, artifact.GetID())
+}
+
+// ArtifactByID downloads the artifact of the ID and extracts it like LatestArtifact, but without listing artifacts.
+// It costs only an API call to get the artifact besides the download. Options to select artifacts are ignored.
+func (d *Downloader) ArtifactByID(ctx context.Context, owner, repo string, artifactID int64, opts Options) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	if err := opts.prepareOutput(); err != nil {
+		return "", err
+	}
+
+	artifact, err := d.GetArtifact(ctx, owner, repo, artifactID)
+	if err != nil {
+		return "", err
+	}
+	if artifact.GetExpired() {
+		return "", fmt.Errorf("artifact %d (%s) has expired and can't be downloaded", artifact.GetID(), artifact.GetName())
+	}
+	if d.OnSelect != nil {
+		d.OnSelect(artifact)
+	}
+	return d.fetch(ctx, owner, repo, artifact, opts)
+}
+
+// GetArtifact returns the artifact of the ID. It wraps ErrNoArtifacts if the artifact is not found.
+func (d *Downloader) GetArtifact(ctx context.Context, owner, repo string, artifactID int64) (*github.Artifact, error) {
+	var artifact *github.Artifact
+	err := d.retry(ctx, fmt.Sprintf("getting artifact(id: %d)", artifactID), func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		artifact, resp, err = d.client.Actions.GetArtifact(ctx, owner, repo, artifactID)
+		d.observeRate("getting artifact", resp)
+		return withStatus(resp, err)
+	})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		// GitHub responds 404 as well for a repository which the token can't read
+		return nil, fmt.Errorf("%w. artifact %d is not in %s/%s, or the token can't read the repository. detail: %v", ErrNoArtifacts, artifactID, owner, repo, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get artifact %d. detail: %w", artifactID, err)
+	}
+	return artifact, nil
+}
+
+// LatestArtifacts downloads the latest artifact for each name in the repository and extracts it into <OutputDir>/<name>.
+// Artifacts are listed only once and shared among the names. Options.Name is ignored.
+// It returns the directories (or the paths of the archives if NoExtract is set) in the order of the names.
+func (d *Downloader) LatestArtifacts(ctx context.Context, owner, repo string, names []string, opts Options) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if len(names) > 1 {
+		if err := validateMulti(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Name = ""
+	// select all of them beforehand not to download some of them when others are missing
+	selected := make([]*github.Artifact, len(names))
+	err := d.poll(ctx, opts, func(ctx context.Context) error {
+		artifacts, err := d.listArtifactsFor(ctx, owner, repo, opts)
+		if err != nil {
+			return err
+		}
+		for i, name := range names {
+			o := opts
+			o.Name = name
+			artifact, err := SelectArtifact(artifacts, o)
+			if err != nil {
+				return fmt.Errorf("%s/%s: %w", owner, repo, err)
+			}
+			selected[i] = artifact
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d.fetchAll(ctx, owner, repo, selected, names, opts)
+}
+
+// RecentArtifacts downloads the count most recent artifacts in the repository, after Options.Offset.
+// Each of them is extracted into <OutputDir>/<name>-<creation time>, e.g. nightly-20240109T030405Z.
+// Artifacts are listed only once. It returns the directories (or the paths of the archives if NoExtract is set) newest first.
+// Fewer artifacts are downloaded with a warning if there are not enough of them.
+func (d *Downloader) RecentArtifacts(ctx context.Context, owner, repo string, count int, opts Options) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("invalid count %d. it must be positive", count)
+	}
+	if count > 1 {
+		if err := validateMulti(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	var selected []*github.Artifact
+	err := d.poll(ctx, opts, func(ctx context.Context) error {
+		artifacts, err := d.listArtifactsFor(ctx, owner, repo, opts)
+		if err != nil {
+			return err
+		}
+		// it fails like LatestArtifact if there are none
+		if _, err := SelectArtifact(artifacts, opts); err != nil {
+			return fmt.Errorf("%s/%s: %w", owner, repo, err)
+		}
+		matched, err := FilterArtifacts(artifacts, opts)
+		if err != nil {
+			return err
+		}
+		selected = matched[opts.Offset:]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) < count {
+		d.logger().Warn("fewer artifacts than the count", "owner", owner, "repo", repo, "count", count, "available", len(selected))
+	} else {
+		selected = selected[:count]
+	}
+
+	dirs := make([]string, len(selected))
+	for i, a := range selected {
+		dirs[i] = a.GetName() + "-" + a.GetCreatedAt().UTC().Format("20060102T150405Z")
+	}
+	return d.fetchAll(ctx, owner, repo, selected, dirs, opts)
+}
+
+// LatestArtifactPerName downloads the latest artifact for each distinct name among the candidates and extracts it into <OutputDir>/<name>.
+// Unlike LatestArtifacts, names don't have to be known in advance. Options.Offset is applied within each name.
+// Artifacts are listed only once. It returns the directories (or the paths of the archives if NoExtract is set) in the order of the selected artifacts.
+func (d *Downloader) LatestArtifactPerName(ctx context.Context, owner, repo string, opts Options) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	// the number of names isn't known until listing
+	if err := validateMulti(opts); err != nil {
+		return nil, err
+	}
+
+	var selected []*github.Artifact
+	err := d.poll(ctx, opts, func(ctx context.Context) error {
+		artifacts, err := d.listArtifactsFor(ctx, owner, repo, opts)
+		if err != nil {
+			return err
+		}
+		o := opts
+		o.Offset = 0
+		// it fails like LatestArtifact if there are none
+		if _, err := SelectArtifact(artifacts, o); err != nil {
+			return fmt.Errorf("%s/%s: %w", owner, repo, err)
+		}
+		matched, err := FilterArtifacts(artifacts, o)
+		if err != nil {
+			return err
+		}
+		selected = latestPerName(matched, opts.Offset)
+		if len(selected) == 0 {
+			return fmt.Errorf("%s/%s: %w at offset %d for any name", owner, repo, ErrNoArtifacts, opts.Offset)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(selected))
+	for i, a := range selected {
+		names[i] = a.GetName()
+	}
+	return d.fetchAll(ctx, owner, repo, selected, names, opts)
+}
+
+// validateMulti rejects options naming a single file, which can't be shared by multiple artifacts.
+func validateMulti(opts Options) error {
+	if opts.ZipPath != "" {
+		return errors.New("the path of the archive can't be given for multiple artifacts")
+	}
+	if opts.Manifest != "" {
+		return errors.New("the manifest can't be written for multiple artifacts")
+	}
+	if opts.ChecksumFile != "" {
+		return errors.New("the checksum file can't be written for multiple artifacts")
+	}
+	return nil
+}
+
+// latestPerName returns the artifact at the offset of each name. artifacts must be sorted already.
+// Names with offset or fewer artifacts are omitted.
+func latestPerName(artifacts []*github.Artifact, offset int) []*github.Artifact {
+	seen := map[string]int{}
+	var selected []*github.Artifact
+	for _, a := range artifacts {
+		if seen[a.GetName()] == offset {
+			selected = append(selected, a)
+		}
+		seen[a.GetName()]++
+	}
+	return selected
+}
+
+// fetchAll downloads artifacts and extracts each of them into the directory under Options.OutputDir.
+// Each download gets a fresh download url.
+func (d *Downloader) fetchAll(ctx context.Context, owner, repo string, artifacts []*github.Artifact, dirs []string, opts Options) ([]string, error) {
+	var paths []string
+	for i, artifact := range artifacts {
+		if d.OnSelect != nil {
+			d.OnSelect(artifact)
+		}
+		o := opts
+		o.OutputDir = filepath.Join(opts.outputDir(), dirs[i])
+		if opts.FS != nil {
+			o.FS = prefixFS{fsys: opts.FS, dir: dirs[i]}
+		}
+		// they are deleted after all of them have succeeded
+		o.DeleteAfterDownload = false
+		if err := o.prepareOutput(); err != nil {
+			return nil, err
+		}
+		path, err := d.fetch(ctx, owner, repo, artifact, o)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dirs[i], err)
+		}
+		paths = append(paths, path)
+	}
+	for _, artifact := range artifacts {
+		if err := d.deleteAfterDownload(ctx, owner, repo, artifact, opts); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// fetch downloads the archive of the artifact and extracts it into Options.OutputDir.
+// It returns the output directory, or the path of the archive if NoExtract is set.
+func (d *Downloader) fetch(ctx context.Context, owner, repo string, artifact *github.Artifact, opts Options) (string, error) {
+	if opts.Stream && !opts.KeepZip && !opts.NoExtract {
+		if artifact.GetSizeInBytes() <= opts.streamMaxBytes() {
+			b, err := d.downloadMemory(ctx, owner, repo, artifact, opts)
+			if err != nil {
+				return "", err
+			}
+			if err := d.ExtractReader(ctx, bytes.NewReader(b), int64(len(b)), opts); err != nil {
+				return "", withArtifact(artifact, err)
+			}
+			d.onDownload(artifact)
+			if err := d.deleteAfterDownload(ctx, owner, repo, artifact, opts); err != nil {
+				return "", err
+			}
+			return opts.outputDir(), nil
+		}
+		d.logger().Info("the artifact is too large to extract in memory. using a temp file", "artifact_id", artifact.GetID(), "bytes", artifact.GetSizeInBytes(), "max_bytes", opts.streamMaxBytes())
+	}
+
+	zipPath, temporary, err := d.downloadArchive(ctx, owner, repo, artifact, opts)
+	if err != nil {
+		return "", err
+	}
+	if temporary {
+		defer os.RemoveAll(zipPath)
+	}
+	path := zipPath
+	if !opts.NoExtract {
+		if err := d.Extract(ctx, zipPath, opts); err != nil {
+			return "", withArtifact(artifact, err)
+		}
+		path = opts.outputDir()
+	}
+	d.onDownload(artifact)
+
+	if err := d.deleteAfterDownload(ctx, owner, repo, artifact, opts); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// withArtifact names the artifact in the error of an empty archive so that the workflow producing it can be investigated.
+func withArtifact(artifact *github.Artifact, err error) error {
+	if !errors.Is(err, ErrEmptyArchive) {
+		return err
+	}
+	return &ExtractError{Err: fmt.Errorf("unable to extract artifact %q (id: %d, workflow run: %d). detail: %w", artifact.GetName(), artifact.GetID(), artifact.GetWorkflowRun().GetID(), ErrEmptyArchive)}
+}
+
+// deleteAfterDownload deletes the artifact if Options.DeleteAfterDownload is set.
+// It must be called only after everything has succeeded.
+func (d *Downloader) deleteAfterDownload(ctx context.Context, owner, repo string, artifact *github.Artifact, opts Options) error {
+	if !opts.DeleteAfterDownload {
+		return nil
+	}
+	if err := d.DeleteArtifact(ctx, owner, repo, artifact.GetID()); err != nil {
+		return err
+	}
+	if d.OnDelete != nil {
+		d.OnDelete(artifact)
+	}
+	return nil
+}
+
+// DeleteArtifact deletes the artifact. The token needs the write permission of actions.
+func (d *Downloader) DeleteArtifact(ctx context.Context, owner, repo string, artifactID int64) error {
+	err := d.retry(ctx, fmt.Sprintf("deleting artifact(id: %d)", artifactID), func() error {
+		resp, err := d.client.Actions.DeleteArtifact(ctx, owner, repo, artifactID)
+		d.observeRate("deleting artifact", resp)
+		return withStatus(resp, err)
+	})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusForbidden || statusErr.StatusCode == http.StatusNotFound) {
+		// GitHub responds 404 as well to hide the existence of the resource from a token without the permission
+		return fmt.Errorf("unable to delete artifact %d. the token needs the write permission of actions. detail: %w", artifactID, err)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete artifact %d. detail: %w", artifactID, err)
+	}
+	d.logger().Info("deleted the artifact", "owner", owner, "repo", repo, "artifact_id", artifactID)
+	return nil
+}
+
+// LatestArtifactTo downloads the latest artifact in the repository and writes its only file into w.
+// It fails if the artifact doesn't consist of exactly one file after Options.ExtractMatch and Options.ExtractExclude.
+// Nothing is written to the disk except the archive kept by Options.KeepZip.
+func (d *Downloader) LatestArtifactTo(ctx context.Context, owner, repo string, opts Options, w io.Writer) error {
+	return d.writeLatestArtifact(ctx, owner, repo, opts, func(zipPath string) error {
+		return ExtractSingle(zipPath, opts, w)
+	})
+}
+
+// LatestArtifactAsTar downloads the latest artifact in the repository and writes its files into w as a tar.gz stream.
+// See WriteTarGz for how they are repackaged. Nothing is written to the disk except the archive kept by Options.KeepZip.
+func (d *Downloader) LatestArtifactAsTar(ctx context.Context, owner, repo string, opts Options, w io.Writer) error {
+	return d.writeLatestArtifact(ctx, owner, repo, opts, func(zipPath string) error {
+		return d.writeTarGz(zipPath, opts, w)
+	})
+}
+
+// writeLatestArtifact downloads the latest artifact in the repository and calls write with the archive instead of extracting it.
+func (d *Downloader) writeLatestArtifact(ctx context.Context, owner, repo string, opts Options, write func(zipPath string) error) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if opts.NoExtract {
+		return errors.New("no-extract can't be used with writing the file")
+	}
+
+	artifact, err := d.selectLatest(ctx, owner, repo, opts)
+	if err != nil {
+		return err
+	}
+
+	zipPath, temporary, err := d.downloadArchive(ctx, owner, repo, artifact, opts)
+	if err != nil {
+		return err
+	}
+	if temporary {
+		defer os.RemoveAll(zipPath)
+	}
+	if err := write(zipPath); err != nil {
+		return err
+	}
+	d.onDownload(artifact)
+	return d.deleteAfterDownload(ctx, owner, repo, artifact, opts)
+}
+
+// LatestArtifactURL selects the latest artifact in the repository and returns the url of its archive without downloading it.
+// See DownloadURL for its lifetime.
+func (d *Downloader) LatestArtifactURL(ctx context.Context, owner, repo string, opts Options) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	artifact, err := d.selectLatest(ctx, owner, repo, opts)
+	if err != nil {
+		return "", err
+	}
+
+	downloadURL, err := d.DownloadURL(ctx, owner, repo, artifact.GetID())
+	if err != nil {
+		return "", err
+	}
+	return downloadURL.String(), nil
+}
+
+// LatestArtifactRunURL selects the latest artifact in the repository and returns the html url of the workflow run which produced it.
+// Nothing is downloaded, but it costs an API call to get the workflow run.
+func (d *Downloader) LatestArtifactRunURL(ctx context.Context, owner, repo string, opts Options) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	artifact, err := d.selectLatest(ctx, owner, repo, opts)
+	if err != nil {
+		return "", err
+	}
+	return d.RunURL(ctx, owner, repo, artifact)
+}
+
+// RunURL returns the html url of the workflow run which produced the artifact.
+func (d *Downloader) RunURL(ctx context.Context, owner, repo string, artifact *github.Artifact) (string, error) {
+	runID := artifact.GetWorkflowRun().GetID()
+	if runID == 0 {
+		return "", fmt.Errorf("artifact %d doesn't tell its workflow run", artifact.GetID())
+	}
+	run, err := d.workflowRun(ctx, owner, repo, runID)
+	if err != nil {
+		return "", err
+	}
+	return run.GetHTMLURL(), nil
+}
+
+// DryRunResult describes what LatestArtifact would do.
+type DryRunResult struct {
+	// Artifact is the selected artifact.
+	Artifact *github.Artifact
+	// Entries are files which would be extracted.
+	Entries []Entry
+}
+
+// DryRun selects the latest artifact and inspects its archive like LatestArtifact, but extracts nothing.
+// The archive is downloaded into a temp file to read its contents.
+func (d *Downloader) DryRun(ctx context.Context, owner, repo string, opts Options) (*DryRunResult, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	artifact, err := d.selectLatest(ctx, owner, repo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	zipPath, err := d.downloadTemp(ctx, owner, repo, artifact, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(zipPath)
+
+	entries, err := ListEntries(zipPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunResult{Artifact: artifact, Entries: entries}, nil
+}
+
+// Candidates returns artifacts in the repository which satisfy the options. They are sorted newest first.
+func (d *Downloader) Candidates(ctx context.Context, owner, repo string, opts Options) ([]*github.Artifact, error) {
+	artifacts, err := d.listArtifactsFor(ctx, owner, repo, opts)
+	if err != nil {
+		return nil, err
+	}
+	return FilterArtifacts(artifacts, opts)
+}
+
+// listArtifactsFor lists artifacts in the repository with information which the options need.
+func (d *Downloader) listArtifactsFor(ctx context.Context, owner, repo string, opts Options) ([]*github.Artifact, error) {
+	var (
+		artifacts []*github.Artifact
+		err       error
+	)
+	if opts.RunID != 0 {
+		artifacts, err = d.listWorkflowRunArtifacts(ctx, owner, repo, opts.RunID, opts)
+	} else {
+		artifacts, err = d.listArtifacts(ctx, owner, repo, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.RunAttempt != 0 {
+		since, before, err := d.runAttemptWindow(ctx, owner, repo, opts.RunID, opts.RunAttempt)
+		if err != nil {
+			return nil, err
+		}
+		var matched []*github.Artifact
+		for _, a := range artifacts {
+			created := a.GetCreatedAt().Time
+			if !created.Before(since) && (before.IsZero() || created.Before(before)) {
+				matched = append(matched, a)
+			}
+		}
+		artifacts = matched
+	}
+	// Listing artifacts of each run of the workflow costs too many API calls,
+	// so artifacts in the repository are narrowed down by their runs instead.
+	if opts.Workflow != "" {
+		runIDs, err := d.workflowRunIDs(ctx, owner, repo, opts.Workflow, artifacts, opts.MaxPages)
+		if err != nil {
+			return nil, err
+		}
+		var matched []*github.Artifact
+		for _, a := range artifacts {
+			if runIDs[a.GetWorkflowRun().GetID()] {
+				matched = append(matched, a)
+			}
+		}
+		artifacts = matched
+	}
+	if opts.Branch != "" || opts.SHA != "" {
+		if err := d.completeWorkflowRuns(ctx, owner, repo, artifacts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SortBy == SORT_BY_RUN_NUMBER {
+		return d.sortByRunNumber(ctx, owner, repo, artifacts, opts)
+	}
+	return artifacts, nil
+}
+
+// selectLatest lists artifacts in the repository and selects the latest one.
+// It waits for the artifact up to Options.WaitTimeout.
+func (d *Downloader) selectLatest(ctx context.Context, owner, repo string, opts Options) (*github.Artifact, error) {
+	var artifact *github.Artifact
+	err := d.poll(ctx, opts, func(ctx context.Context) error {
+		artifacts, err := d.listArtifactsFor(ctx, owner, repo, opts)
+		if err != nil {
+			return err
+		}
+		artifact, err = SelectArtifact(artifacts, opts)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %w", owner, repo, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.logger().Debug("selected an artifact", "owner", owner, "repo", repo, "artifact_id", artifact.GetID(), "name", artifact.GetName(), "bytes", artifact.GetSizeInBytes())
+	if d.OnSelect != nil {
+		d.OnSelect(artifact)
+	}
+	return artifact, nil
+}
+
+// downloadArchive downloads the archive of the artifact into Options.ZipPath if it is kept, otherwise into a temp file.
+// temporary reports whether the caller is responsible for removing it.
+func (d *Downloader) downloadArchive(ctx context.Context, owner, repo string, artifact *github.Artifact, opts Options) (zipPath string, temporary bool, err error) {
+	if opts.KeepZip || opts.NoExtract {
+		zipPath = opts.zipPath(artifact)
+		if err := d.downloadFile(ctx, owner, repo, artifact, zipPath, opts); err != nil {
+			return "", false, err
+		}
+		return zipPath, false, nil
+	}
+	zipPath, err = d.downloadTemp(ctx, owner, repo, artifact, opts)
+	if err != nil {
+		return "", false, err
+	}
+	return zipPath, true, nil
+}
+
+// downloadFile downloads the archive of the artifact into the path.
+// The file is removed on failure not to leave a broken archive.
+func (d *Downloader) downloadFile(ctx context.Context, owner, repo string, artifact *github.Artifact, path string, opts Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create zip file. detail: %w", err)
+	}
+	if err := d.downloadInto(ctx, owner, repo, artifact, f, opts); err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+	return nil
+}
+
+// downloadTemp downloads the archive of the artifact into a temp file and returns its path.
+// The caller is responsible for removing the file.
+func (d *Downloader) downloadTemp(ctx context.Context, owner, repo string, artifact *github.Artifact, opts Options) (string, error) {
+	temp, err := os.CreateTemp(opts.TempDir, "get-the-latest-artifact-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temp file. detail: %w", err)
+	}
+	if err := d.downloadInto(ctx, owner, repo, artifact, temp, opts); err != nil {
+		os.RemoveAll(temp.Name())
+		return "", err
+	}
+	return temp.Name(), nil
+}
+
+// downloadInto downloads the archive of the artifact into f and closes it.
+// The digest of the archive is verified against Options.ArchiveSHA256 if set.
+func (d *Downloader) downloadInto(ctx context.Context, owner, repo string, artifact *github.Artifact, f *os.File, opts Options) error {
+	defer f.Close()
+
+	if err := checkSize(artifact, opts); err != nil {
+		return err
+	}
+	h := sha256.New()
+	if err := d.download(ctx, owner, repo, artifact.GetID(), io.MultiWriter(f, h), opts.MaxSize); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close zip file. detail: %w", err)
+	}
+	return d.verifyDigest(h, opts, "artifact_id", artifact.GetID())
+}
+
+// downloadMemory downloads the archive of the artifact into memory.
+// The digest of the archive is verified against Options.ArchiveSHA256 if set.
+func (d *Downloader) downloadMemory(ctx context.Context, owner, repo string, artifact *github.Artifact, opts Options) ([]byte, error) {
+	if err := checkSize(artifact, opts); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(artifact.GetSizeInBytes()))
+	h := sha256.New()
+	if err := d.download(ctx, owner, repo, artifact.GetID(), io.MultiWriter(&buf, h), opts.MaxSize); err != nil {
+		return nil, err
+	}
+	if err := d.verifyDigest(h, opts, "artifact_id", artifact.GetID()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkSize refuses the artifact larger than Options.MaxSize before downloading it.
+func checkSize(artifact *github.Artifact, opts Options) error {
+	if opts.MaxSize > 0 && artifact.GetSizeInBytes() > opts.MaxSize {
+		return fmt.Errorf("artifact %d (%s) is %d bytes, which exceeds max-size %d bytes", artifact.GetID(), artifact.GetName(), artifact.GetSizeInBytes(), opts.MaxSize)
+	}
+	return nil
+}
+
+// verifyDigest logs the digest of the archive and compares it with Options.ArchiveSHA256 if set.
+// attrs tell which archive it is, e.g. "artifact_id" and the ID.
+func (d *Downloader) verifyDigest(h hash.Hash, opts Options, attrs ...any) error {
+	digest := hex.EncodeToString(h.Sum(nil))
+	attrs = append(attrs, "sha256", digest)
+	d.logger().Debug("computed the digest of the archive", attrs...)
+	if opts.ArchiveSHA256 == "" {
+		return nil
+	}
+	if !strings.EqualFold(digest, opts.ArchiveSHA256) {
+		return fmt.Errorf("sha256 of the archive mismatched. expected: %s, actual: %s", opts.ArchiveSHA256, digest)
+	}
+	d.logger().Info("verified the archive", attrs...)
+	return nil
+}
@@ -0,0 +1,143 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// newTestDownloader returns a Downloader calling the API served by the handler.
+func newTestDownloader(t *testing.T, handler http.Handler) *Downloader {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client := github.NewClient(srv.Client())
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	d := New(client)
+	d.HTTPClient = srv.Client()
+	return d
+}
+
+// artifactIDs returns IDs of the artifacts in their order.
+func artifactIDs(artifacts []*github.Artifact) []int64 {
+	ids := make([]int64, len(artifacts))
+	for i, a := range artifacts {
+		ids[i] = a.GetID()
+	}
+	return ids
+}
+
+func TestMatchName(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts Options
+		want bool
+	}{
+		{name: "build", opts: Options{}, want: true},
+		{name: "build", opts: Options{Name: "build"}, want: true},
+		{name: "build-linux", opts: Options{Name: "build"}, want: false},
+		{name: "build-linux", opts: Options{NameGlob: "build-*"}, want: true},
+		{name: "coverage", opts: Options{NameGlob: "build-*"}, want: false},
+		// an exclusion wins over the name and the pattern
+		{name: "build", opts: Options{Name: "build", ExcludeNames: []string{"build"}}, want: false},
+		{name: "build-windows", opts: Options{NameGlob: "build-*", ExcludeNames: []string{"*-windows"}}, want: false},
+		{name: "build-linux", opts: Options{NameGlob: "build-*", ExcludeNames: []string{"*-windows"}}, want: true},
+		{name: "coverage", opts: Options{ExcludeNames: []string{"build-*", "coverage"}}, want: false},
+		{name: "docs", opts: Options{ExcludeNames: []string{"build-*", "coverage"}}, want: true},
+		// IgnoreCase applies to exclusions as well
+		{name: "Build-Windows", opts: Options{NameGlob: "build-*", IgnoreCase: true}, want: true},
+		{name: "Build-Windows", opts: Options{NameGlob: "build-*", ExcludeNames: []string{"*-windows"}, IgnoreCase: true}, want: false},
+		{name: "Build-Windows", opts: Options{ExcludeNames: []string{"*-windows"}}, want: true},
+	} {
+		if got := tt.opts.matchName(tt.name); got != tt.want {
+			t.Errorf("matchName(%q) with name %q, name-glob %q, exclude-name %q, ignore-case %v = %v, want %v",
+				tt.name, tt.opts.Name, tt.opts.NameGlob, tt.opts.ExcludeNames, tt.opts.IgnoreCase, got, tt.want)
+		}
+	}
+}
+
+func TestLatestArtifactFallsBackOnMissing(t *testing.T) {
+	archive, err := os.ReadFile(writeZip(t, []zipEntry{{name: "a.txt", body: "a"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/actions/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":2,"artifacts":[
+			{"id":2,"name":"build","created_at":"2024-01-02T00:00:00Z"},
+			{"id":1,"name":"build","created_at":"2024-01-01T00:00:00Z"}]}`)
+	})
+	// the newest one has been deleted since it was listed
+	mux.HandleFunc("/repos/o/r/actions/artifacts/2/zip", http.NotFound)
+	mux.HandleFunc("/repos/o/r/actions/artifacts/1/zip", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+r.Host+"/archive", http.StatusFound)
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { w.Write(archive) })
+	d := newTestDownloader(t, mux)
+	var selected, downloaded []*github.Artifact
+	d.OnSelect = func(a *github.Artifact) { selected = append(selected, a) }
+	d.OnDownload = func(a *github.Artifact) { downloaded = append(downloaded, a) }
+	dir := t.TempDir()
+
+	if _, err := d.LatestArtifact(context.Background(), "o", "r", Options{OutputDir: dir, FallbackOnMissing: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "a.txt"), "a")
+	if got := artifactIDs(selected); !slices.Equal(got, []int64{2, 1}) {
+		t.Errorf("selected %v, want [2 1]", got)
+	}
+	if got := artifactIDs(downloaded); !slices.Equal(got, []int64{1}) {
+		t.Errorf("downloaded %v, want [1]", got)
+	}
+}
+
+func TestVerifyDigestLogs(t *testing.T) {
+	const digest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" // sha256 of "hello"
+	for _, tt := range []struct {
+		name     string
+		expected string
+		wantErr  bool
+		want     []string
+	}{
+		{name: "not expected", want: []string{"computed the digest of the archive"}},
+		{name: "matched", expected: strings.ToUpper(digest), want: []string{"computed the digest of the archive", "verified the archive"}},
+		{name: "mismatched", expected: strings.Repeat("0", 64), wantErr: true, want: []string{"computed the digest of the archive"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			d := New(nil)
+			d.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			h := sha256.New()
+			h.Write([]byte("hello"))
+
+			err := d.verifyDigest(h, Options{ArchiveSHA256: tt.expected}, "artifact_id", 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyDigest() = %v, want error: %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if !strings.Contains(line, "sha256="+digest) {
+					t.Errorf("the digest isn't logged: %s", line)
+				}
+				_, msg, _ := strings.Cut(line, "msg=")
+				msg, _, _ = strings.Cut(msg, " artifact_id=")
+				got = append(got, strings.Trim(msg, `"`))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
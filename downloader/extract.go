@@ -0,0 +1,688 @@
+package downloader
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policies for Options.Overwrite
+const (
+	OVERWRITE_ALWAYS = "always"
+	OVERWRITE_NEVER  = "never"
+	OVERWRITE_ERROR  = "error"
+)
+
+// Policies for Options.FlattenCollision
+const (
+	FLATTEN_COLLISION_ERROR  = "error"
+	FLATTEN_COLLISION_SUFFIX = "suffix"
+)
+
+// errUnsafeSymlink is returned for a symlink pointing outside of the output directory.
+var errUnsafeSymlink = errors.New("symlink escapes the output directory")
+
+// ErrEmptyArchive is returned when Options.FailOnEmptyExtract is set and the archive has no files to extract.
+var ErrEmptyArchive = errors.New("the archive has no files to extract")
+
+// ExtractError is returned when the archive can't be extracted, so that it can be told from failures of the download.
+type ExtractError struct {
+	Err error
+}
+
+func (e *ExtractError) Error() string { return e.Err.Error() }
+func (e *ExtractError) Unwrap() error { return e.Err }
+
+// Extract extracts all files in the zip archive into Options.OutputDir.
+// Files are extracted concurrently by Options.ExtractConcurrency workers.
+// Failures are returned as *ExtractError.
+func (d *Downloader) Extract(ctx context.Context, zipPath string, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := d.extract(ctx, zipPath, opts); err != nil {
+		return &ExtractError{Err: err}
+	}
+	return nil
+}
+
+// ExtractReader extracts all files in the zip archive read from r like Extract. size is the size of the archive.
+// It is used to extract an archive in memory without a temp file.
+func (d *Downloader) ExtractReader(ctx context.Context, r io.ReaderAt, size int64, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return &ExtractError{Err: fmt.Errorf("unable to open zip reader. detail: %w", err)}
+	}
+	if err := d.extractZip(ctx, zr, opts); err != nil {
+		return &ExtractError{Err: err}
+	}
+	return nil
+}
+
+func (d *Downloader) extract(ctx context.Context, zipPath string, opts Options) error {
+	// unzip
+	zipfile, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("unable to open zip reader. detail: %w", err)
+	}
+	defer zipfile.Close()
+	return d.extractZip(ctx, &zipfile.Reader, opts)
+}
+
+func (d *Downloader) extractZip(ctx context.Context, zipfile *zip.Reader, opts Options) error {
+	if err := normalizeNames(zipfile.File); err != nil {
+		return fmt.Errorf("unable to extract the artifact. detail: %w", err)
+	}
+	zipfile.File = stripComponents(zipfile.File, opts.StripComponents)
+	// It is told before anything is made in the output directory.
+	if opts.FailOnEmptyExtract && !hasFilesToExtract(zipfile, opts) {
+		return ErrEmptyArchive
+	}
+	// Only the directory on the OS has symlinks and existing files to care about.
+	onDisk := opts.FS == nil
+	outputDir := opts.outputDir()
+	fsys := opts.FS
+	if onDisk {
+		if err := prepareOutputDir(outputDir); err != nil {
+			return err
+		}
+		fsys = DirFS(outputDir)
+	} else {
+		outputDir = "."
+	}
+
+	// Directories and symlinks are made beforehand so that workers don't race on them.
+	var (
+		jobs     []extractJob
+		symlinks []string
+		skipped  int
+		flat     = newFlattener(opts.FlattenCollision)
+		// Files are written after all symlinks are made, wherever they come in the archive.
+		// An entry under one of them would be written wherever it points, e.g. through chained ones like "a -> ..", "a/b -> ..".
+		links = symlinkNames(zipfile.File, opts)
+	)
+	for _, file := range zipfile.File {
+		dstPath, err := resolveExtractPath(outputDir, file.Name)
+		if err != nil {
+			return fmt.Errorf("unable to extract the artifact. detail: %w", err)
+		}
+		name := strings.TrimSuffix(file.Name, "/")
+
+		if link := underSymlink(name, links); onDisk && !opts.Flatten && link != "" {
+			err := fmt.Errorf("%w: %q is under symlink %q", errUnsafeSymlink, file.Name, link)
+			if opts.SkipUnsafeSymlinks {
+				d.logger().Warn("skipped an entry under a symlink", "name", file.Name, "error", err)
+				skipped++
+				continue
+			}
+			return fmt.Errorf("unable to extract the artifact. detail: %w", err)
+		}
+
+		// entries for directories have no content
+		if file.FileInfo().IsDir() {
+			if opts.Flatten {
+				continue
+			}
+			if err := fsys.MkdirAll(opts.prefixed(name), 0o755); err != nil {
+				return fmt.Errorf("unable to create dst directory. detail: %w", err)
+			}
+			continue
+		}
+
+		if !opts.shouldExtract(file.Name) {
+			skipped++
+			continue
+		}
+
+		if opts.Flatten {
+			name, err = flat.rename(file.Name)
+			if err != nil {
+				return fmt.Errorf("unable to extract the artifact. detail: %w", err)
+			}
+			dstPath = filepath.Join(outputDir, name)
+		}
+		if opts.Prefix != "" {
+			name = opts.prefixed(name)
+			dstPath = filepath.Join(outputDir, filepath.FromSlash(name))
+		}
+
+		if opts.Overwrite == OVERWRITE_NEVER || opts.Overwrite == OVERWRITE_ERROR {
+			if _, err := os.Lstat(dstPath); err == nil {
+				if opts.Overwrite == OVERWRITE_ERROR {
+					return fmt.Errorf("unable to extract the artifact. detail: %q already exists", dstPath)
+				}
+				d.logger().Debug("skipped an existing file", "name", file.Name, "path", dstPath)
+				skipped++
+				continue
+			}
+		}
+
+		// a zip doesn't always have entries for parent directories
+		if dir := path.Dir(name); dir != "." {
+			if err := fsys.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("unable to create parent directory of dst file. detail: %w", err)
+			}
+		}
+
+		if file.Mode()&os.ModeSymlink != 0 {
+			if !onDisk {
+				d.logger().Warn("skipped a symlink which can't be made in the fs", "name", file.Name)
+				skipped++
+				continue
+			}
+			err := osFS{root: outputDir}.within(path.Dir(name))
+			if err == nil {
+				err = extractSymlink(file, outputDir, dstPath)
+			}
+			if err != nil {
+				if errors.Is(err, errUnsafeSymlink) && opts.SkipUnsafeSymlinks {
+					d.logger().Warn("skipped an unsafe symlink", "name", file.Name, "error", err)
+					skipped++
+					continue
+				}
+				return fmt.Errorf("unable to extract the artifact. detail: %w", err)
+			}
+			d.onFile(file.Name, 0)
+			symlinks = append(symlinks, dstPath)
+			continue
+		}
+
+		if !onDisk {
+			dstPath = name
+		}
+		jobs = append(jobs, extractJob{file: file, name: name, dstPath: dstPath})
+	}
+
+	results, err := d.runExtractJobs(ctx, fsys, jobs, opts.extractConcurrency(), opts.ChecksumFile != "")
+	if err != nil {
+		return err
+	}
+	d.logger().Info("extracted the artifact", "output_dir", outputDir, "files", len(jobs)+len(symlinks), "skipped", skipped)
+
+	if opts.Manifest != "" {
+		var entries []ManifestEntry
+		for i, job := range jobs {
+			entries = append(entries, ManifestEntry{Path: job.dstPath, Size: results[i].size})
+		}
+		for _, path := range symlinks {
+			info, err := os.Lstat(path)
+			if err != nil {
+				return fmt.Errorf("unable to stat symlink %q. detail: %w", path, err)
+			}
+			entries = append(entries, ManifestEntry{Path: path, Size: info.Size()})
+		}
+		if err := writeManifest(opts.Manifest, entries); err != nil {
+			return err
+		}
+	}
+	if opts.ChecksumFile != "" {
+		var checksums []checksum
+		for i, job := range jobs {
+			// it differs from the name in the archive if flattened
+			checksums = append(checksums, checksum{name: job.name, digest: results[i].digest})
+		}
+		if err := writeChecksumFile(opts.ChecksumFile, checksums); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// symlinkNames returns names of symlinks in the archive which are extracted.
+func symlinkNames(files []*zip.File, opts Options) map[string]bool {
+	links := map[string]bool{}
+	for _, file := range files {
+		if file.Mode()&os.ModeSymlink != 0 && opts.shouldExtract(file.Name) {
+			links[strings.TrimSuffix(file.Name, "/")] = true
+		}
+	}
+	return links
+}
+
+// underSymlink returns the symlink among links which the name is under, or "" if none.
+func underSymlink(name string, links map[string]bool) string {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if links[dir] {
+			return dir
+		}
+	}
+	return ""
+}
+
+// hasFilesToExtract reports whether the archive has a file which satisfies ExtractMatch and ExtractExclude.
+func hasFilesToExtract(zipfile *zip.Reader, opts Options) bool {
+	for _, file := range zipfile.File {
+		if !file.FileInfo().IsDir() && opts.shouldExtract(file.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractJob is a regular file to be extracted.
+type extractJob struct {
+	file *zip.File
+	// name is the name in the WriterFS
+	name string
+	// dstPath is the path on the OS, or the name if it is extracted into Options.FS
+	dstPath string
+}
+
+// extractResult is the result of an extractJob.
+type extractResult struct {
+	size int64
+	// digest is the SHA-256 digest of the content if it is asked
+	digest []byte
+}
+
+// runExtractJobs extracts files by the number of workers and returns the result for each job.
+// The digest of each file is computed as well if withDigest is set.
+// It returns the first error, and the rest of jobs are canceled on it.
+func (d *Downloader) runExtractJobs(ctx context.Context, fsys WriterFS, jobs []extractJob, workers int, withDigest bool) ([]extractResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	// each worker writes results of different jobs
+	results := make([]extractResult, len(jobs))
+	ch := make(chan int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				if ctx.Err() != nil {
+					continue
+				}
+				job := jobs[i]
+				written, digest, err := extractRegularFile(job.file, fsys, job.name, withDigest)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("unable to extract the artifact. detail: %w", err)
+						cancel()
+					})
+					continue
+				}
+				if written != int64(job.file.UncompressedSize64) {
+					d.logger().Warn("size mismatched", "name", job.file.Name, "expected_bytes", job.file.UncompressedSize64, "bytes", written)
+				}
+				d.onFile(job.file.Name, written)
+				results[i] = extractResult{size: written, digest: digest}
+			}
+		}()
+	}
+	for i := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, ctx.Err()
+}
+
+// extractRegularFile writes the content of the entry as the name in fsys.
+// Files are closed within it, not to keep file descriptors open for all entries.
+// It returns the number of bytes written.
+func extractRegularFile(file *zip.File, fsys WriterFS, name string, withDigest bool) (int64, []byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to open src file. detail: %w", err)
+	}
+	defer rc.Close()
+	var (
+		src io.Reader = rc
+		h   hash.Hash
+	)
+	if withDigest {
+		// the digest is of what is written, without reading the file again
+		h = sha256.New()
+		src = io.TeeReader(rc, h)
+	}
+
+	// keep permissions like the executable bit
+	perm := file.Mode().Perm()
+	if perm == 0 {
+		perm = 0o666
+	}
+	dst, err := fsys.Create(name, perm)
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to create dst file. detail: %w", err)
+	}
+	written, copyErr := io.Copy(dst, src)
+	// a write error may be reported on close
+	closeErr := dst.Close()
+	if copyErr != nil {
+		// don't leave a truncated file
+		if remover, ok := fsys.(interface{ Remove(name string) error }); ok {
+			remover.Remove(name)
+		}
+		return 0, nil, fmt.Errorf("unable to write dst file %q. detail: %w", name, copyErr)
+	}
+	if closeErr != nil {
+		return 0, nil, fmt.Errorf("unable to close dst file %q. detail: %w", name, closeErr)
+	}
+
+	if chtimer, ok := fsys.(interface {
+		Chtimes(name string, mtime time.Time) error
+	}); ok && !file.Modified.IsZero() {
+		if err := chtimer.Chtimes(name, file.Modified); err != nil {
+			return 0, nil, fmt.Errorf("unable to set modification time of dst file. detail: %w", err)
+		}
+	}
+	var digest []byte
+	if h != nil {
+		digest = h.Sum(nil)
+	}
+	return written, digest, nil
+}
+
+// readSymlink returns the target of the symlink entry.
+func readSymlink(file *zip.File) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("unable to open src file. detail: %w", err)
+	}
+	defer src.Close()
+	// a link target longer than PATH_MAX is invalid anyway
+	b, err := io.ReadAll(io.LimitReader(src, 4096))
+	if err != nil {
+		return "", fmt.Errorf("unable to read symlink %q. detail: %w", file.Name, err)
+	}
+	return string(b), nil
+}
+
+// checkSymlinkTarget fails with errUnsafeSymlink unless the target of the symlink at dstPath stays inside of root.
+// It is checked as text, since the target may not exist yet.
+func checkSymlinkTarget(name, target, root, dstPath string) error {
+	if filepath.IsAbs(target) {
+		return fmt.Errorf("%w: %q -> %q", errUnsafeSymlink, name, target)
+	}
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Join(filepath.Dir(dstPath), target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %q -> %q", errUnsafeSymlink, name, target)
+	}
+	return nil
+}
+
+// extractSymlink recreates the symlink entry at dstPath.
+// The link target is stored as the content of the entry. It must stay inside root.
+func extractSymlink(file *zip.File, root, dstPath string) error {
+	target, err := readSymlink(file)
+	if err != nil {
+		return err
+	}
+	if err := checkSymlinkTarget(file.Name, target, root, dstPath); err != nil {
+		return err
+	}
+
+	// os.Symlink doesn't replace an existing file
+	if err := os.Remove(dstPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to replace %q with symlink. detail: %w", dstPath, err)
+	}
+	if err := os.Symlink(target, dstPath); err != nil {
+		return fmt.Errorf("unable to create symlink. detail: %w", err)
+	}
+	return nil
+}
+
+// ExtractSingle writes the content of the only file in the zip archive into w.
+// Files are narrowed down by Options.ExtractMatch and Options.ExtractExclude beforehand.
+// Failures are returned as *ExtractError.
+func ExtractSingle(zipPath string, opts Options, w io.Writer) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := extractSingle(zipPath, opts, w); err != nil {
+		return &ExtractError{Err: err}
+	}
+	return nil
+}
+
+func extractSingle(zipPath string, opts Options, w io.Writer) error {
+	zipfile, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("unable to open zip reader. detail: %w", err)
+	}
+	defer zipfile.Close()
+	if err := normalizeNames(zipfile.File); err != nil {
+		return fmt.Errorf("unable to extract the artifact. detail: %w", err)
+	}
+	zipfile.File = stripComponents(zipfile.File, opts.StripComponents)
+
+	var files []*zip.File
+	for _, file := range zipfile.File {
+		if file.FileInfo().IsDir() || !opts.shouldExtract(file.Name) {
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("the artifact has %d files. writing to stdout requires a single-file artifact (narrow it down with extract-match)", len(files))
+	}
+	file := files[0]
+	if file.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%q is a symlink, not a regular file", file.Name)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open src file. detail: %w", err)
+	}
+	defer src.Close()
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("unable to write %q. detail: %w", file.Name, err)
+	}
+	return nil
+}
+
+// Entry is a file in an archive.
+type Entry struct {
+	// Name is the name in the archive.
+	Name string
+	// Path is where the file is extracted.
+	Path string
+	// Exists reports whether something already exists at Path, which Options.Overwrite applies to.
+	Exists bool
+}
+
+// ListEntries returns files in the zip archive which would be extracted with their destinations, without extracting them.
+// Whether each destination already exists is told as well.
+func ListEntries(zipPath string, opts Options) ([]Entry, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	outputDir := opts.outputDir()
+	zipfile, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open zip reader. detail: %w", err)
+	}
+	defer zipfile.Close()
+	if err := normalizeNames(zipfile.File); err != nil {
+		return nil, fmt.Errorf("unable to inspect the artifact. detail: %w", err)
+	}
+	zipfile.File = stripComponents(zipfile.File, opts.StripComponents)
+
+	var entries []Entry
+	flat := newFlattener(opts.FlattenCollision)
+	for _, file := range zipfile.File {
+		if file.FileInfo().IsDir() || !opts.shouldExtract(file.Name) {
+			continue
+		}
+		dstPath, err := resolveExtractPath(outputDir, file.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to inspect the artifact. detail: %w", err)
+		}
+		name := file.Name
+		if opts.Flatten {
+			name, err = flat.rename(file.Name)
+			if err != nil {
+				return nil, fmt.Errorf("unable to inspect the artifact. detail: %w", err)
+			}
+			dstPath = filepath.Join(outputDir, name)
+		}
+		if opts.Prefix != "" {
+			dstPath = filepath.Join(outputDir, filepath.FromSlash(opts.prefixed(name)))
+		}
+		_, err = os.Lstat(dstPath)
+		entries = append(entries, Entry{Name: file.Name, Path: dstPath, Exists: err == nil})
+	}
+	return entries, nil
+}
+
+// prepareOutputDir creates the directory if it doesn't exist.
+func prepareOutputDir(outputDir string) error {
+	info, err := os.Stat(outputDir)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("output-dir %q exists but is not a directory", outputDir)
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to stat output-dir %q. detail: %w", outputDir, err)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("unable to create output-dir %q. detail: %w", outputDir, err)
+	}
+	return nil
+}
+
+// normalizeNames rewrites names of zip entries into the form of slash-separated relative paths.
+// An archive made on Windows may separate them by backslashes. Names with NUL bytes or drive letters are rejected,
+// because they can't be extracted portably. Escaping the output directory is rejected by resolveExtractPath.
+func normalizeNames(files []*zip.File) error {
+	for _, file := range files {
+		name := file.Name
+		if strings.ContainsRune(name, 0) {
+			return fmt.Errorf("illegal zip entry %q: it contains a NUL byte", name)
+		}
+		name = strings.ReplaceAll(name, `\`, "/")
+		// e.g. "C:/Users" or "C:foo" which is relative to the current directory of the drive
+		if len(name) >= 2 && name[1] == ':' && ('a' <= name[0] && name[0] <= 'z' || 'A' <= name[0] && name[0] <= 'Z') {
+			return fmt.Errorf("illegal zip entry %q: it has a drive letter", file.Name)
+		}
+		isDir := strings.HasSuffix(name, "/")
+		name = path.Clean(name)
+		if isDir && !strings.HasSuffix(name, "/") {
+			// the trailing slash tells a directory
+			name += "/"
+		}
+		file.Name = name
+	}
+	return nil
+}
+
+// validatePrefix rejects a prefix which would put files outside of the output directory.
+func validatePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	p := strings.ReplaceAll(prefix, `\`, "/")
+	if path.IsAbs(p) || filepath.IsAbs(prefix) || len(p) >= 2 && p[1] == ':' {
+		return fmt.Errorf("invalid prefix %q. it must be a relative path", prefix)
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." {
+			return fmt.Errorf("invalid prefix %q. it must not contain ..", prefix)
+		}
+	}
+	return nil
+}
+
+// prefixed joins Options.Prefix in front of the name in the archive. A trailing slash of a directory is kept.
+func (o Options) prefixed(name string) string {
+	if o.Prefix == "" {
+		return name
+	}
+	joined := path.Join(strings.ReplaceAll(o.Prefix, `\`, "/"), name)
+	if strings.HasSuffix(name, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// stripComponents removes n leading directories from names of zip entries, which must have been normalized.
+// Entries without more than n segments, like the leading directories themselves, are dropped.
+func stripComponents(files []*zip.File, n int) []*zip.File {
+	if n == 0 {
+		return files
+	}
+	var stripped []*zip.File
+	for _, file := range files {
+		segments := strings.Split(strings.TrimSuffix(file.Name, "/"), "/")
+		if len(segments) <= n {
+			continue
+		}
+		name := strings.Join(segments[n:], "/")
+		if strings.HasSuffix(file.Name, "/") {
+			name += "/"
+		}
+		file.Name = name
+		stripped = append(stripped, file)
+	}
+	return stripped
+}
+
+// flattener names files in a flat directory by their base names.
+type flattener struct {
+	policy string
+	used   map[string]bool
+}
+
+func newFlattener(policy string) *flattener {
+	return &flattener{policy: policy, used: map[string]bool{}}
+}
+
+// rename returns the base name of the entry. When it has been taken by another entry,
+// FLATTEN_COLLISION_SUFFIX numbers it before the extension like "report-1.pdf", and FLATTEN_COLLISION_ERROR fails.
+func (f *flattener) rename(name string) (string, error) {
+	base := path.Base(name)
+	renamed := base
+	for i := 1; f.used[renamed]; i++ {
+		if f.policy != FLATTEN_COLLISION_SUFFIX {
+			return "", fmt.Errorf("zip entry %q collides with another one as %q when flattened", name, base)
+		}
+		ext := path.Ext(base)
+		renamed = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), i, ext)
+	}
+	f.used[renamed] = true
+	return renamed, nil
+}
+
+// resolveExtractPath returns the path where the zip entry is written.
+// It rejects entries escaping the root directory (a.k.a. Zip Slip) like "../../etc/cron.d/evil".
+func resolveExtractPath(root, name string) (string, error) {
+	dst := filepath.Join(root, name)
+	rel, err := filepath.Rel(filepath.Clean(root), dst)
+	if err != nil {
+		return "", fmt.Errorf("illegal zip entry %q: %w", name, err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("illegal zip entry %q: it escapes the output directory", name)
+	}
+	return dst, nil
+}
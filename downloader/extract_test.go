@@ -0,0 +1,294 @@
+package downloader
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// zipEntry is an entry of an archive made by writeZip. A name ending with a slash is a directory.
+type zipEntry struct {
+	name string
+	body string
+	mode fs.FileMode
+}
+
+// writeZip writes the entries into an archive in a temp directory and returns its path.
+// Names are written as is, so that malformed archives can be made as well.
+func writeZip(t *testing.T, entries []zipEntry) string {
+	t.Helper()
+	zipPath := filepath.Join(t.TempDir(), "artifact.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			h.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+// assertFile fails unless the file at the path has the content.
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("%s = %q, want %q", path, b, want)
+	}
+}
+
+func TestExtractNestedPaths(t *testing.T) {
+	// parent directories don't always have their own entries
+	zipPath := writeZip(t, []zipEntry{
+		{name: "top.txt", body: "top"},
+		{name: "a/b/c.txt", body: "deep"},
+		{name: "docs/"},
+		{name: "docs/guide/index.html", body: "<html>"},
+	})
+	dir := filepath.Join(t.TempDir(), "out")
+
+	if err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "top.txt"), "top")
+	assertFile(t, filepath.Join(dir, "a", "b", "c.txt"), "deep")
+	assertFile(t, filepath.Join(dir, "docs", "guide", "index.html"), "<html>")
+	if info, err := os.Stat(filepath.Join(dir, "docs")); err != nil || !info.IsDir() {
+		t.Errorf("docs is not a directory: %v", err)
+	}
+}
+
+func TestExtractRejectsZipSlip(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "a/../../evil.txt", `..\evil.txt`} {
+		t.Run(name, func(t *testing.T) {
+			zipPath := writeZip(t, []zipEntry{
+				{name: "ok.txt", body: "ok"},
+				{name: name, body: "evil"},
+			})
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "out")
+
+			err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir})
+			var extractErr *ExtractError
+			if !errors.As(err, &extractErr) {
+				t.Fatalf("Extract() = %v, want *ExtractError", err)
+			}
+			if _, err := os.Stat(filepath.Join(parent, "evil.txt")); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("a file is written outside of the output directory: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractKeepsModes(t *testing.T) {
+	zipPath := writeZip(t, []zipEntry{
+		{name: "bin/run.sh", body: "#!/bin/sh\n", mode: 0o755},
+		{name: "README", body: "readme", mode: 0o644},
+	})
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits")
+	}
+	dir := t.TempDir()
+
+	if err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]fs.FileMode{"bin/run.sh": 0o755, "README": 0o644} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("mode of %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExtractRejectsUnsafeSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need a privilege on Windows")
+	}
+	link := fs.ModeSymlink | 0o777
+	for _, tt := range []struct {
+		name    string
+		entries []zipEntry
+	}{
+		{
+			name:    "outside",
+			entries: []zipEntry{{name: "up", body: "../evil.txt", mode: link}},
+		},
+		{
+			name:    "absolute",
+			entries: []zipEntry{{name: "up", body: "/tmp", mode: link}},
+		},
+		{
+			// each link stays inside by itself, but the file is written through both of them
+			name: "chained",
+			entries: []zipEntry{
+				{name: "sub/"},
+				{name: "sub/up", body: "..", mode: link},
+				{name: "sub/up/up2", body: "..", mode: link},
+				{name: "sub/up/up2/evil.txt", body: "evil"},
+			},
+		},
+		{
+			// files are written after links are made, even if they come first
+			name: "file before links",
+			entries: []zipEntry{
+				{name: "sub/up/up2/evil.txt", body: "evil"},
+				{name: "sub/up", body: "..", mode: link},
+				{name: "sub/up/up2", body: "..", mode: link},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			zipPath := writeZip(t, tt.entries)
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "out")
+
+			err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir})
+			if !errors.Is(err, errUnsafeSymlink) {
+				t.Errorf("Extract() = %v, want %v", err, errUnsafeSymlink)
+			}
+			if _, err := os.Stat(filepath.Join(parent, "evil.txt")); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("a file is written outside of the output directory: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractInsideSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need a privilege on Windows")
+	}
+	zipPath := writeZip(t, []zipEntry{
+		{name: "bin/run.sh", body: "run"},
+		{name: "run", body: "bin/run.sh", mode: fs.ModeSymlink | 0o777},
+		{name: "bin/current", body: ".", mode: fs.ModeSymlink | 0o777},
+	})
+	dir := t.TempDir()
+
+	if err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	target, err := os.Readlink(filepath.Join(dir, "run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "bin/run.sh" {
+		t.Errorf("link target = %q, want %q", target, "bin/run.sh")
+	}
+	assertFile(t, filepath.Join(dir, "run"), "run")
+	assertFile(t, filepath.Join(dir, "bin", "current", "run.sh"), "run")
+}
+
+func TestExtractThroughExistingSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need a privilege on Windows")
+	}
+	zipPath := writeZip(t, []zipEntry{{name: "up/evil.txt", body: "evil"}})
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "out")
+	// e.g. left by an earlier extraction
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(dir, "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir})
+	if !errors.Is(err, errUnsafeSymlink) {
+		t.Errorf("Extract() = %v, want %v", err, errUnsafeSymlink)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "evil.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a file is written outside of the output directory: %v", err)
+	}
+}
+
+func TestExtractConcurrently(t *testing.T) {
+	const n = 500
+	var entries []zipEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, zipEntry{name: fmt.Sprintf("reports/%d/%d.txt", i%7, i), body: strconv.Itoa(i)})
+	}
+	zipPath := writeZip(t, entries)
+	dir := t.TempDir()
+	d := New(nil)
+	var files []string
+	// calls are serialized, so it doesn't need a lock
+	d.OnFile = func(name string, size int64) { files = append(files, name) }
+
+	if err := d.Extract(context.Background(), zipPath, Options{OutputDir: dir, ExtractConcurrency: 16}); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != n {
+		t.Errorf("OnFile is called %d times, want %d", len(files), n)
+	}
+	for _, e := range entries {
+		assertFile(t, filepath.Join(dir, filepath.FromSlash(e.name)), e.body)
+	}
+}
+
+func TestExtractWindowsNames(t *testing.T) {
+	// e.g. made by Compress-Archive of older PowerShell
+	zipPath := writeZip(t, []zipEntry{
+		{name: `dist\`},
+		{name: `dist\bin\app.exe`, body: "app"},
+		{name: `dist\.\README.txt`, body: "readme"},
+	})
+	dir := t.TempDir()
+
+	if err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir, ExtractMatch: "dist/bin/*"}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "dist", "bin", "app.exe"), "app")
+	if _, err := os.Stat(filepath.Join(dir, "dist", "README.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("README.txt doesn't satisfy extract-match, but it is extracted: %v", err)
+	}
+
+	if err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "dist", "README.txt"), "readme")
+}
+
+func TestExtractRejectsIllegalNames(t *testing.T) {
+	for _, name := range []string{`C:\Windows\evil.txt`, "c:evil.txt", "evil\x00.txt"} {
+		t.Run(name, func(t *testing.T) {
+			zipPath := writeZip(t, []zipEntry{{name: name, body: "evil"}})
+
+			err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: t.TempDir()})
+			var extractErr *ExtractError
+			if !errors.As(err, &extractErr) {
+				t.Errorf("Extract() = %v, want *ExtractError", err)
+			}
+		})
+	}
+}
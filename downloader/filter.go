@@ -0,0 +1,174 @@
+package downloader
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// Keys of a filter, which are fields of an artifact
+const (
+	FILTER_KEY_NAME    = "name"
+	FILTER_KEY_SIZE    = "size"
+	FILTER_KEY_CREATED = "created"
+	FILTER_KEY_UPDATED = "updated"
+	FILTER_KEY_EXPIRED = "expired"
+)
+
+// Operators of a filter
+const (
+	FILTER_OP_EQ = "eq"
+	FILTER_OP_NE = "ne"
+	FILTER_OP_LT = "lt"
+	FILTER_OP_LE = "le"
+	FILTER_OP_GT = "gt"
+	FILTER_OP_GE = "ge"
+	// It matches a name by a pattern of path.Match.
+	FILTER_OP_GLOB = "glob"
+)
+
+// operators allowed for each key
+var filterOps = map[string][]string{
+	FILTER_KEY_NAME:    {FILTER_OP_EQ, FILTER_OP_NE, FILTER_OP_GLOB},
+	FILTER_KEY_SIZE:    {FILTER_OP_EQ, FILTER_OP_NE, FILTER_OP_LT, FILTER_OP_LE, FILTER_OP_GT, FILTER_OP_GE},
+	FILTER_KEY_CREATED: {FILTER_OP_LT, FILTER_OP_LE, FILTER_OP_GT, FILTER_OP_GE},
+	FILTER_KEY_UPDATED: {FILTER_OP_LT, FILTER_OP_LE, FILTER_OP_GT, FILTER_OP_GE},
+	FILTER_KEY_EXPIRED: {FILTER_OP_EQ, FILTER_OP_NE},
+}
+
+// Filter is a predicate on a field of an artifact, e.g. "size=gt:10M". Make it by ParseFilter.
+type Filter struct {
+	key   string
+	op    string
+	value string
+
+	// the value parsed for the key
+	number  int64
+	time    time.Time
+	boolean bool
+}
+
+// ParseFilter parses a filter in the form of key=op:value.
+//   - name: eq, ne or glob with a string, e.g. "name=glob:build-*"
+//   - size: eq, ne, lt, le, gt or ge with bytes. A suffix K, M or G multiplies it by 1024, e.g. "size=le:10M"
+//   - created, updated: lt, le, gt or ge with a RFC3339 timestamp or a duration ago, e.g. "created=gt:24h" for the last 24 hours
+//   - expired: eq or ne with true or false
+func ParseFilter(s string) (Filter, error) {
+	key, rest, ok := strings.Cut(s, "=")
+	if !ok {
+		return Filter{}, fmt.Errorf("invalid filter %q. it must be in the form of key=op:value", s)
+	}
+	op, value, ok := strings.Cut(rest, ":")
+	if !ok {
+		return Filter{}, fmt.Errorf("invalid filter %q. it must be in the form of key=op:value", s)
+	}
+	ops, ok := filterOps[key]
+	if !ok {
+		return Filter{}, fmt.Errorf("invalid filter %q. the key must be one of %s, %s, %s, %s, %s", s, FILTER_KEY_NAME, FILTER_KEY_SIZE, FILTER_KEY_CREATED, FILTER_KEY_UPDATED, FILTER_KEY_EXPIRED)
+	}
+	if !slices.Contains(ops, op) {
+		return Filter{}, fmt.Errorf("invalid filter %q. the operator for %s must be one of %s", s, key, strings.Join(ops, ", "))
+	}
+
+	f := Filter{key: key, op: op, value: value}
+	var err error
+	switch key {
+	case FILTER_KEY_NAME:
+		if op == FILTER_OP_GLOB {
+			_, err = path.Match(value, "")
+		}
+	case FILTER_KEY_SIZE:
+		f.number, err = parseBytes(value)
+	case FILTER_KEY_CREATED, FILTER_KEY_UPDATED:
+		f.time, err = ParseTimeOrAgo(value)
+	case FILTER_KEY_EXPIRED:
+		f.boolean, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid filter %q. detail: %w", s, err)
+	}
+	return f, nil
+}
+
+func (f Filter) String() string { return f.key + "=" + f.op + ":" + f.value }
+
+// Match reports whether the artifact satisfies the filter.
+func (f Filter) Match(a *github.Artifact) bool {
+	switch f.key {
+	case FILTER_KEY_NAME:
+		switch f.op {
+		case FILTER_OP_GLOB:
+			// the pattern has been validated
+			matched, _ := path.Match(f.value, a.GetName())
+			return matched
+		case FILTER_OP_NE:
+			return a.GetName() != f.value
+		default:
+			return a.GetName() == f.value
+		}
+	case FILTER_KEY_SIZE:
+		return compareBy(f.op, a.GetSizeInBytes(), f.number)
+	case FILTER_KEY_CREATED:
+		return compareBy(f.op, int64(a.GetCreatedAt().Time.Compare(f.time)), 0)
+	case FILTER_KEY_UPDATED:
+		return compareBy(f.op, int64(a.GetUpdatedAt().Time.Compare(f.time)), 0)
+	case FILTER_KEY_EXPIRED:
+		return (a.GetExpired() == f.boolean) == (f.op == FILTER_OP_EQ)
+	default:
+		return false
+	}
+}
+
+func compareBy(op string, x, y int64) bool {
+	switch op {
+	case FILTER_OP_EQ:
+		return x == y
+	case FILTER_OP_NE:
+		return x != y
+	case FILTER_OP_LT:
+		return x < y
+	case FILTER_OP_LE:
+		return x <= y
+	case FILTER_OP_GT:
+		return x > y
+	case FILTER_OP_GE:
+		return x >= y
+	default:
+		return false
+	}
+}
+
+// parseBytes parses bytes with an optional suffix K, M or G, e.g. "10M".
+func parseBytes(s string) (int64, error) {
+	multiplier := int64(1)
+	for i, suffix := range []string{"K", "M", "G"} {
+		if strings.HasSuffix(s, suffix) {
+			s = strings.TrimSuffix(s, suffix)
+			multiplier = 1 << (10 * (i + 1))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not bytes (e.g. 1024 or 10M)", s)
+	}
+	return n * multiplier, nil
+}
+
+// ParseTimeOrAgo parses a RFC3339 timestamp or a duration ago, e.g. "24h".
+// A duration is taken from now, when it is parsed.
+func ParseTimeOrAgo(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration (e.g. 24h) nor a RFC3339 timestamp", s)
+	}
+	return t, nil
+}
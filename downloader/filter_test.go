@@ -0,0 +1,30 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeOrAgo(t *testing.T) {
+	got, err := ParseTimeOrAgo("2024-01-02T03:04:05Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ParseTimeOrAgo() = %v, want %v", got, want)
+	}
+
+	before := time.Now()
+	got, err = ParseTimeOrAgo("24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+	if got.Before(before.Add(-24*time.Hour)) || got.After(after.Add(-24*time.Hour)) {
+		t.Errorf("ParseTimeOrAgo() = %v, want 24h before now", got)
+	}
+
+	if _, err := ParseTimeOrAgo("yesterday"); err == nil {
+		t.Error("ParseTimeOrAgo() succeeds for neither a duration nor a timestamp")
+	}
+}
@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ManifestEntry is an extracted file in the manifest.
+type ManifestEntry struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+}
+
+// writeManifest writes entries into the path as a JSON array.
+func writeManifest(path string, entries []ManifestEntry) error {
+	for i := range entries {
+		abs, err := filepath.Abs(entries[i].Path)
+		if err != nil {
+			return fmt.Errorf("unable to resolve the absolute path of %q. detail: %w", entries[i].Path, err)
+		}
+		entries[i].Path = abs
+	}
+	if entries == nil {
+		// an empty array instead of null
+		entries = []ManifestEntry{}
+	}
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode the manifest. detail: %w", err)
+	}
+	return writeFileAtomically(path, "manifest", append(b, '\n'))
+}
+
+// writeFileAtomically writes b into the path. what names the file in errors.
+// It is written into a temp file and renamed so that a partial run doesn't leave a half-written file.
+func writeFileAtomically(path, what string, b []byte) error {
+	// the temp file is in the same directory so that it can be renamed atomically
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create the %s. detail: %w", what, err)
+	}
+	defer os.Remove(temp.Name())
+	// CreateTemp makes it readable only by the owner
+	if err := temp.Chmod(0o644); err != nil {
+		temp.Close()
+		return fmt.Errorf("unable to write the %s. detail: %w", what, err)
+	}
+	if _, err := temp.Write(b); err != nil {
+		temp.Close()
+		return fmt.Errorf("unable to write the %s. detail: %w", what, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("unable to write the %s. detail: %w", what, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("unable to write the %s. detail: %w", what, err)
+	}
+	return nil
+}
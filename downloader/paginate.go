@@ -0,0 +1,47 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// paginate calls fetch for each page until there are no additional pages, fetch tells to stop, or maxPages is reached.
+// maxPages is unlimited if it is 0.
+// fetch is retried on transient failures, and waits for the rate limit if configured.
+func (d *Downloader) paginate(ctx context.Context, what string, maxPages int, fetch func(opts *github.ListOptions) (resp *github.Response, stop bool, err error)) error {
+	page := 1
+	for {
+		var (
+			resp *github.Response
+			stop bool
+		)
+		err := d.retry(ctx, fmt.Sprintf("listing %s(page: %d)", what, page), func() error {
+			var err error
+			resp, stop, err = fetch(&github.ListOptions{PerPage: d.perPage(), Page: page})
+			d.observeRate("listing "+what, resp)
+			return err
+		})
+		if err != nil {
+			if err := d.waitForRateLimit(ctx, err); err != nil {
+				return fmt.Errorf("unable to list %s. page: %d, detail: %w", what, page, err)
+			}
+			// the rate limit has been reset, so try the same page again
+			continue
+		}
+		d.logger().Debug("fetched a page", "what", what, "page", page, "next_page", resp.NextPage)
+		if stop {
+			return nil
+		}
+		if maxPages > 0 && page >= maxPages && resp.NextPage != 0 {
+			d.logger().Info("stopped listing at the max pages", "what", what, "max_pages", maxPages)
+			return nil
+		}
+		page = resp.NextPage
+		// if there are no additional pages
+		if page == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// artifactPages serves total artifacts, newest first, by pages of per_page. No Link headers are sent,
+// like proxies omitting them, so the total count is the only hint of more pages. It counts requests for each page.
+func artifactPages(total int, requests map[int]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		requests[page]++
+		list := &github.ArtifactList{TotalCount: github.Int64(int64(total))}
+		for i := (page - 1) * perPage; i < min(page*perPage, total); i++ {
+			list.Artifacts = append(list.Artifacts, &github.Artifact{
+				ID:        github.Int64(int64(total - i)),
+				Name:      github.String(fmt.Sprintf("artifact-%d", total-i)),
+				CreatedAt: &github.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, total-i, 0, time.UTC)},
+			})
+		}
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+func TestListArtifactsFullLastPage(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		total int
+		// wantPages is the number of pages requested
+		wantPages int
+	}{
+		{name: "exactly a page", total: 100, wantPages: 1},
+		{name: "two full pages", total: 200, wantPages: 2},
+		{name: "a partial page", total: 150, wantPages: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := map[int]int{}
+			d := newTestDownloader(t, artifactPages(tt.total, requests))
+
+			artifacts, err := d.ListArtifacts(context.Background(), "o", "r")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(artifacts) != tt.total {
+				t.Errorf("listed %d artifacts, want %d", len(artifacts), tt.total)
+			}
+			if len(requests) != tt.wantPages {
+				t.Errorf("requested pages %v, want %d pages", requests, tt.wantPages)
+			}
+			for page, n := range requests {
+				if n != 1 {
+					t.Errorf("page %d is requested %d times", page, n)
+				}
+			}
+		})
+	}
+}
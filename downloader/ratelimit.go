@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// waitForRateLimit sleeps until the rate limit resets when err is caused by the rate limit.
+// It returns nil after waiting. Otherwise it returns an error which tells when the limit resets.
+func (d *Downloader) waitForRateLimit(ctx context.Context, err error) error {
+	var rateLimitErr *github.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return err
+	}
+	reset := rateLimitErr.Rate.Reset.Time
+	limitErr := fmt.Errorf("rate limited, resets at %s. detail: %w", reset.Format(time.RFC3339), err)
+	if !d.WaitForRateLimit {
+		return limitErr
+	}
+	if deadline, ok := ctx.Deadline(); ok && reset.After(deadline) {
+		return limitErr
+	}
+
+	// Clients limited at once would retry at once when it resets, so they are spread.
+	wait := time.Until(reset) + jitter(d.retryBackoff())
+	d.logger().Warn("rate limited, waiting until it resets", "reset", reset.Format(time.RFC3339), "wait", wait)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w. %v", ctx.Err(), limitErr)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateUsage summarizes the rate limit observed in responses of the GitHub API.
+type RateUsage struct {
+	// Requests is the number of responses which tell the rate limit.
+	Requests int
+	// Consumed is how much budget the requests consumed. Other clients sharing the token may be counted as well.
+	Consumed int
+	// Last is the rate limit told by the last response.
+	Last github.Rate
+}
+
+// observeRate logs the rate limit told by resp and records it for RateUsage.
+func (d *Downloader) observeRate(what string, resp *github.Response) {
+	// a failure before receiving the response, or a server which doesn't tell it
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+	d.logger().Debug("rate limit", "what", what, "limit", resp.Rate.Limit, "remaining", resp.Rate.Remaining, "reset", resp.Rate.Reset.Format(time.RFC3339))
+
+	d.rateMu.Lock()
+	defer d.rateMu.Unlock()
+	if d.rateUsage.Requests == 0 {
+		// the first request has consumed one already
+		d.rateUsage.Consumed = 1
+	} else if resp.Rate.Reset.Equal(d.rateUsage.Last.Reset) {
+		d.rateUsage.Consumed += d.rateUsage.Last.Remaining - resp.Rate.Remaining
+	} else {
+		// the limit has been reset in between
+		d.rateUsage.Consumed += resp.Rate.Limit - resp.Rate.Remaining
+	}
+	d.rateUsage.Requests++
+	d.rateUsage.Last = resp.Rate
+}
+
+// RateUsage returns the summary of the rate limit observed so far. ok is false if no responses told it.
+func (d *Downloader) RateUsage() (usage RateUsage, ok bool) {
+	d.rateMu.Lock()
+	defer d.rateMu.Unlock()
+	return d.rateUsage, d.rateUsage.Requests > 0
+}
@@ -0,0 +1,202 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// LatestReleaseAsset downloads an asset of the latest release in the repository, which satisfies Options.Name and Options.NameGlob.
+// A zip asset is handled like the archive of an artifact, and others are saved as they are into Options.FS if set, otherwise into Options.OutputDir.
+// MaxSize and ArchiveSHA256 apply to the asset as well.
+// It returns the output directory, the path of the kept or saved asset, or its name in FS.
+func (d *Downloader) LatestReleaseAsset(ctx context.Context, owner, repo string, opts Options) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	if err := opts.prepareOutput(); err != nil {
+		return "", err
+	}
+	outputDir := opts.outputDir()
+
+	var release *github.RepositoryRelease
+	err := d.retry(ctx, "getting the latest release", func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		release, resp, err = d.client.Repositories.GetLatestRelease(ctx, owner, repo)
+		d.observeRate("getting the latest release", resp)
+		return withStatus(resp, err)
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to get the latest release. detail: %w", err)
+	}
+
+	asset, err := selectAsset(release, opts)
+	if err != nil {
+		return "", fmt.Errorf("%s/%s: %w", owner, repo, err)
+	}
+	d.logger().Info("selected a release asset", "owner", owner, "repo", repo, "release", release.GetTagName(), "asset_id", asset.GetID(), "name", asset.GetName(), "bytes", asset.GetSize())
+
+	if !strings.HasSuffix(strings.ToLower(asset.GetName()), ".zip") {
+		return d.saveAsset(ctx, owner, repo, asset, opts)
+	}
+
+	if opts.KeepZip || opts.NoExtract {
+		zipPath := filepath.Join(outputDir, asset.GetName())
+		if opts.ZipPath != "" {
+			zipPath = opts.ZipPath
+		}
+		if err := d.downloadAssetFile(ctx, owner, repo, asset, zipPath, opts); err != nil {
+			return "", err
+		}
+		if opts.NoExtract {
+			return zipPath, nil
+		}
+		if err := d.Extract(ctx, zipPath, opts); err != nil {
+			return "", err
+		}
+		return outputDir, nil
+	}
+
+	if opts.Stream {
+		if int64(asset.GetSize()) <= opts.streamMaxBytes() {
+			var buf bytes.Buffer
+			buf.Grow(asset.GetSize())
+			if err := d.downloadAsset(ctx, owner, repo, asset, &buf, opts); err != nil {
+				return "", err
+			}
+			if err := d.ExtractReader(ctx, bytes.NewReader(buf.Bytes()), int64(buf.Len()), opts); err != nil {
+				return "", err
+			}
+			return outputDir, nil
+		}
+		d.logger().Info("the release asset is too large to extract in memory. using a temp file", "asset_id", asset.GetID(), "bytes", asset.GetSize(), "max_bytes", opts.streamMaxBytes())
+	}
+
+	temp, err := os.CreateTemp(opts.TempDir, "get-the-latest-artifact-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temp file. detail: %w", err)
+	}
+	temp.Close()
+	defer os.RemoveAll(temp.Name())
+	if err := d.downloadAssetFile(ctx, owner, repo, asset, temp.Name(), opts); err != nil {
+		return "", err
+	}
+	if err := d.Extract(ctx, temp.Name(), opts); err != nil {
+		return "", err
+	}
+	return outputDir, nil
+}
+
+// selectAsset returns the first asset of the release which satisfies the options.
+// The only asset is selected if neither Name nor NameGlob is given.
+func selectAsset(release *github.RepositoryRelease, opts Options) (*github.ReleaseAsset, error) {
+	if opts.Name == "" && opts.NameGlob == "" && len(release.Assets) > 1 {
+		return nil, fmt.Errorf("release %s has %d assets. narrow them down with name or name-glob", release.GetTagName(), len(release.Assets))
+	}
+	for _, asset := range release.Assets {
+		if opts.matchName(asset.GetName()) {
+			return asset, nil
+		}
+	}
+	return nil, fmt.Errorf("%w in release %s", ErrNoArtifacts, release.GetTagName())
+}
+
+// saveAsset saves the asset which isn't a zip as it is into Options.FS if set, otherwise into Options.OutputDir.
+// It returns the name in FS, or the path of the saved asset.
+func (d *Downloader) saveAsset(ctx context.Context, owner, repo string, asset *github.ReleaseAsset, opts Options) (string, error) {
+	dstPath, err := resolveExtractPath(opts.outputDir(), asset.GetName())
+	if err != nil {
+		return "", err
+	}
+	name := asset.GetName()
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = DirFS(opts.outputDir())
+	}
+	w, err := fsys.Create(name, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("unable to create file for release asset. detail: %w", err)
+	}
+	downloadErr := d.downloadAsset(ctx, owner, repo, asset, w, opts)
+	closeErr := w.Close()
+	if downloadErr != nil || closeErr != nil {
+		if remover, ok := fsys.(interface{ Remove(name string) error }); ok {
+			remover.Remove(name)
+		}
+		if downloadErr != nil {
+			return "", downloadErr
+		}
+		return "", fmt.Errorf("unable to write release asset %q. detail: %w", asset.GetName(), closeErr)
+	}
+	if opts.FS != nil {
+		return name, nil
+	}
+	return dstPath, nil
+}
+
+// downloadAssetFile downloads the release asset into the path.
+// The file is removed on failure not to leave a broken one.
+func (d *Downloader) downloadAssetFile(ctx context.Context, owner, repo string, asset *github.ReleaseAsset, path string, opts Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create file for release asset. detail: %w", err)
+	}
+	downloadErr := d.downloadAsset(ctx, owner, repo, asset, f, opts)
+	closeErr := f.Close()
+	if downloadErr != nil || closeErr != nil {
+		os.Remove(path)
+		if downloadErr != nil {
+			return downloadErr
+		}
+		return fmt.Errorf("unable to write release asset %q. detail: %w", asset.GetName(), closeErr)
+	}
+	return nil
+}
+
+// downloadAsset writes the release asset to w.
+// It is refused beyond Options.MaxSize like an artifact, and its digest is verified against Options.ArchiveSHA256 if set.
+func (d *Downloader) downloadAsset(ctx context.Context, owner, repo string, asset *github.ReleaseAsset, w io.Writer, opts Options) error {
+	if opts.MaxSize > 0 && int64(asset.GetSize()) > opts.MaxSize {
+		return fmt.Errorf("release asset %d (%s) is %d bytes, which exceeds max-size %d bytes", asset.GetID(), asset.GetName(), asset.GetSize(), opts.MaxSize)
+	}
+	var rc io.ReadCloser
+	err := d.retry(ctx, "getting release asset", func() error {
+		var err error
+		// The redirect to the storage is followed by HTTPClient, which doesn't send the token.
+		rc, _, err = d.client.Repositories.DownloadReleaseAsset(ctx, owner, repo, asset.GetID(), d.httpClient())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get release asset %q. detail: %w", asset.GetName(), err)
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	w = io.MultiWriter(w, h)
+	if d.OnProgress != nil {
+		w = &progressWriter{w: w, total: int64(asset.GetSize()), onProgress: d.OnProgress}
+	}
+	var r io.Reader = rc
+	if opts.MaxSize > 0 {
+		// the size in the release may lie as well, so a byte over the cap tells that it is exceeded
+		r = io.LimitReader(rc, opts.MaxSize+1)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return fmt.Errorf("unable to write release asset %q. detail: %w", asset.GetName(), err)
+	}
+	if opts.MaxSize > 0 && n > opts.MaxSize {
+		return fmt.Errorf("release asset %q exceeds max-size %d bytes", asset.GetName(), opts.MaxSize)
+	}
+	return d.verifyDigest(h, opts, "asset_id", asset.GetID())
+}
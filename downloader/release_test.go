@@ -0,0 +1,116 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// releaseHandler serves the latest release which has the only asset with the body.
+func releaseHandler(name string, body []byte) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v1","assets":[{"id":1,"name":%q,"size":%d}]}`, name, len(body))
+	})
+	mux.HandleFunc("/repos/o/r/releases/assets/1", func(w http.ResponseWriter, r *http.Request) { w.Write(body) })
+	return mux
+}
+
+func TestLatestReleaseAssetSafeguards(t *testing.T) {
+	body := []byte("#!/bin/sh\n")
+	sum := sha256.Sum256(body)
+	for _, tt := range []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{name: "max-size", opts: Options{MaxSize: 4}, wantErr: "exceeds max-size"},
+		{name: "sha256 mismatched", opts: Options{ArchiveSHA256: strings.Repeat("0", 64)}, wantErr: "mismatched"},
+		{name: "sha256 matched", opts: Options{ArchiveSHA256: hex.EncodeToString(sum[:])}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloader(t, releaseHandler("install.sh", body))
+			tt.opts.OutputDir = t.TempDir()
+
+			path, err := d.LatestReleaseAsset(context.Background(), "o", "r", tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LatestReleaseAsset() = %v, want an error with %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(tt.opts.OutputDir, "install.sh")); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("a broken asset is left: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertFile(t, path, string(body))
+		})
+	}
+}
+
+func TestLatestReleaseAssetIntoFS(t *testing.T) {
+	archive, err := os.ReadFile(writeZip(t, []zipEntry{{name: "a.txt", body: "a"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name  string
+		asset string
+		body  []byte
+		want  map[string]string
+	}{
+		{name: "zip", asset: "dist.zip", body: archive, want: map[string]string{"a.txt": "a"}},
+		{name: "other", asset: "install.sh", body: []byte("#!/bin/sh\n"), want: map[string]string{"install.sh": "#!/bin/sh\n"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloader(t, releaseHandler(tt.asset, tt.body))
+			var fsys MemFS
+			// the disk must be left untouched
+			dir := filepath.Join(t.TempDir(), "out")
+
+			if _, err := d.LatestReleaseAsset(context.Background(), "o", "r", Options{OutputDir: dir, FS: &fsys, Stream: true}); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				b, err := fsys.ReadFile(name)
+				if err != nil || string(b) != want {
+					t.Errorf("%s = %q, %v, want %q", name, b, err, want)
+				}
+			}
+			if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("output-dir is created: %v", err)
+			}
+		})
+	}
+}
+
+func TestLatestReleaseAssetNoExtract(t *testing.T) {
+	archive, err := os.ReadFile(writeZip(t, []zipEntry{{name: "a.txt", body: "a"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDownloader(t, releaseHandler("dist.zip", archive))
+	dir := t.TempDir()
+
+	path, err := d.LatestReleaseAsset(context.Background(), "o", "r", Options{OutputDir: dir, NoExtract: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "dist.zip"); path != want {
+		t.Errorf("LatestReleaseAsset() = %q, want %q", path, want)
+	}
+	assertFile(t, path, string(archive))
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the asset is extracted with no-extract: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// ListRepositories returns names of repositories in the organization which match the pattern. See path.Match for the syntax.
+func (d *Downloader) ListRepositories(ctx context.Context, org, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid repo-glob pattern %q. detail: %w", pattern, err)
+	}
+	var names []string
+	err := d.paginate(ctx, fmt.Sprintf("repositories of %s", org), 0, func(lo *github.ListOptions) (*github.Response, bool, error) {
+		repos, resp, err := d.client.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{ListOptions: *lo})
+		if err != nil {
+			return resp, false, err
+		}
+		for _, r := range repos {
+			// the pattern has been validated above
+			if ok, _ := path.Match(pattern, r.GetName()); ok {
+				names = append(names, r.GetName())
+			}
+		}
+		return resp, false, nil
+	})
+	return names, err
+}
+
+// Repository is a repository to look for artifacts in.
+type Repository struct {
+	Owner string
+	Name  string
+}
+
+func (r Repository) String() string { return r.Owner + "/" + r.Name }
+
+// LatestArtifactAcross downloads the latest artifact among the repositories of the owner and extracts it.
+// Artifacts of all repositories are listed, so it costs API calls at least as many as the repositories.
+// It returns the repository which the artifact belongs to, and the same path as LatestArtifact.
+func (d *Downloader) LatestArtifactAcross(ctx context.Context, owner string, repos []string, opts Options) (string, string, error) {
+	targets := make([]Repository, len(repos))
+	for i, r := range repos {
+		targets[i] = Repository{Owner: owner, Name: r}
+	}
+	repo, path, err := d.LatestArtifactAmong(ctx, targets, opts)
+	return repo.Name, path, err
+}
+
+// LatestArtifactAmong downloads the latest artifact among the repositories, which may belong to different owners, and extracts it.
+// Artifacts of all repositories are listed, so it costs API calls at least as many as the repositories.
+// It returns the repository which the artifact belongs to, and the same path as LatestArtifact.
+func (d *Downloader) LatestArtifactAmong(ctx context.Context, repos []Repository, opts Options) (Repository, string, error) {
+	if err := opts.validate(); err != nil {
+		return Repository{}, "", err
+	}
+	if err := opts.prepareOutput(); err != nil {
+		return Repository{}, "", err
+	}
+
+	var (
+		artifact *github.Artifact
+		repo     Repository
+	)
+	err := d.poll(ctx, opts, func(ctx context.Context) error {
+		var all []*github.Artifact
+		repoOf := map[*github.Artifact]Repository{}
+		for _, r := range repos {
+			artifacts, err := d.listArtifactsFor(ctx, r.Owner, r.Name, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r, err)
+			}
+			for _, a := range artifacts {
+				repoOf[a] = r
+			}
+			all = append(all, artifacts...)
+		}
+		var err error
+		artifact, err = SelectArtifact(all, opts)
+		if err != nil {
+			return fmt.Errorf("%d repositories: %w", len(repos), err)
+		}
+		repo = repoOf[artifact]
+		return nil
+	})
+	if err != nil {
+		return Repository{}, "", err
+	}
+	d.logger().Debug("selected an artifact", "owner", repo.Owner, "repo", repo.Name, "artifact_id", artifact.GetID(), "name", artifact.GetName(), "bytes", artifact.GetSizeInBytes())
+	if d.OnSelect != nil {
+		d.OnSelect(artifact)
+	}
+
+	path, err := d.fetch(ctx, repo.Owner, repo.Name, artifact, opts)
+	if err != nil {
+		return Repository{}, "", err
+	}
+	return repo, path, nil
+}
+
+// LatestArtifactEach downloads the latest artifact of each repository like LatestArtifact into <OutputDir>/<owner>/<repo>.
+// A failure of a repository doesn't stop the others, and failures are joined into the returned error.
+// It returns the paths in the order of the repositories. The path is empty for a failed one.
+func (d *Downloader) LatestArtifactEach(ctx context.Context, repos []Repository, opts Options) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if len(repos) > 1 {
+		if err := validateMulti(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, len(repos))
+	var errs []error
+	for i, r := range repos {
+		o := opts
+		o.OutputDir = filepath.Join(opts.outputDir(), r.Owner, r.Name)
+		if opts.FS != nil {
+			o.FS = prefixFS{fsys: opts.FS, dir: path.Join(r.Owner, r.Name)}
+		}
+		path, err := d.LatestArtifact(ctx, r.Owner, r.Name, o)
+		if err != nil {
+			// the rest would fail as well
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			d.logger().Warn("unable to get the latest artifact", "owner", r.Owner, "repo", r.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", r, err))
+			continue
+		}
+		paths[i] = path
+	}
+	return paths, errors.Join(errs...)
+}
@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// The interval is doubled on each retry up to MAX_RETRY_BACKOFF.
+// Each sleep is randomized between 0 and the interval (full jitter), so that clients failing at once don't retry at once.
+const (
+	INITIAL_RETRY_BACKOFF = 1 * time.Second
+	MAX_RETRY_BACKOFF     = 30 * time.Second
+)
+
+// statusError represents a response which has an unexpected status code.
+type statusError struct {
+	StatusCode int
+	err        error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// HTTPStatus returns the status code of the response.
+func (e *statusError) HTTPStatus() int { return e.StatusCode }
+
+// withStatus annotates err with the status code of resp so that it can be judged whether retryable.
+func withStatus(resp *github.Response, err error) error {
+	if err == nil || resp == nil || resp.Response == nil {
+		return err
+	}
+	return &statusError{StatusCode: resp.StatusCode, err: err}
+}
+
+// isNotFound reports whether err is caused by a 404 response.
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// statusCode returns the status code of the response which caused err, or 0 if it isn't caused by a response.
+func statusCode(err error) int {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		return errResp.Response.StatusCode
+	}
+	return 0
+}
+
+// isRetryable reports whether the error is transient.
+// Network errors and 5xx/429 responses are retryable, but other 4xx responses like 404 are not.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// it is handled separately because waiting for a while doesn't help
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.StatusCode)
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		return isRetryableStatus(errResp.Response.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retry calls f until it succeeds, it fails with an error which is not retryable, or retries are exhausted.
+// It gives up without waiting when the next attempt is beyond the deadline of ctx.
+func (d *Downloader) retry(ctx context.Context, what string, f func() error) error {
+	interval := d.retryBackoff()
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt > d.Retries || !isRetryable(err) {
+			return err
+		}
+		backoff := jitter(interval)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		d.logger().Warn("retrying", "what", what, "backoff", backoff, "attempt", attempt, "retries", d.Retries, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w. last error: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+		interval = min(interval*2, d.maxRetryBackoff())
+	}
+}
+
+// jitter returns a random duration between 0 and d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func (d *Downloader) retryBackoff() time.Duration {
+	if d.RetryBackoff > 0 {
+		return min(d.RetryBackoff, d.maxRetryBackoff())
+	}
+	return min(INITIAL_RETRY_BACKOFF, d.maxRetryBackoff())
+}
+
+func (d *Downloader) maxRetryBackoff() time.Duration {
+	if d.MaxRetryBackoff > 0 {
+		return d.MaxRetryBackoff
+	}
+	return MAX_RETRY_BACKOFF
+}
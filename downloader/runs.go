@@ -0,0 +1,210 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// MAX_WORKFLOW_RUN_SPAN bounds how long before its artifacts a workflow run is created.
+// A run lasts up to 35 days, and can be re-run within 30 days, which adds artifacts to the same run.
+const MAX_WORKFLOW_RUN_SPAN = 65 * 24 * time.Hour
+
+// workflowRun returns the workflow run. Results are cached because artifacts in the same run share it.
+func (d *Downloader) workflowRun(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRun, error) {
+	d.runsMu.Lock()
+	run, ok := d.runs[runID]
+	d.runsMu.Unlock()
+	if ok {
+		return run, nil
+	}
+
+	err := d.retry(ctx, fmt.Sprintf("getting workflow run(id: %d)", runID), func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		run, resp, err = d.client.Actions.GetWorkflowRunByID(ctx, owner, repo, runID)
+		d.observeRate("getting workflow run", resp)
+		return withStatus(resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get workflow run. id: %d, detail: %w", runID, err)
+	}
+
+	d.runsMu.Lock()
+	if d.runs == nil {
+		d.runs = map[int64]*github.WorkflowRun{}
+	}
+	d.runs[runID] = run
+	d.runsMu.Unlock()
+	return run, nil
+}
+
+// runAttemptWindow returns the period when the attempt of the workflow run ran.
+// Artifacts don't tell which attempt produced them, so the ones created in the period are regarded as produced by it.
+// before is zero for the latest attempt.
+func (d *Downloader) runAttemptWindow(ctx context.Context, owner, repo string, runID int64, attempt int) (since, before time.Time, err error) {
+	run, err := d.workflowRun(ctx, owner, repo, runID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if attempt > run.GetRunAttempt() {
+		return time.Time{}, time.Time{}, fmt.Errorf("workflow run %d has no attempt %d. the latest one is %d", runID, attempt, run.GetRunAttempt())
+	}
+	started, err := d.runAttemptStartedAt(ctx, owner, repo, runID, attempt)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if attempt < run.GetRunAttempt() {
+		// artifacts of the attempt are created before the next attempt starts
+		before, err = d.runAttemptStartedAt(ctx, owner, repo, runID, attempt+1)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return started, before, nil
+}
+
+// runAttemptStartedAt returns when the attempt of the workflow run started.
+func (d *Downloader) runAttemptStartedAt(ctx context.Context, owner, repo string, runID int64, attempt int) (time.Time, error) {
+	var run *github.WorkflowRun
+	err := d.retry(ctx, fmt.Sprintf("getting attempt %d of workflow run(id: %d)", attempt, runID), func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		run, resp, err = d.client.Actions.GetWorkflowRunAttempt(ctx, owner, repo, runID, attempt, nil)
+		d.observeRate("getting workflow run attempt", resp)
+		return withStatus(resp, err)
+	})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return time.Time{}, fmt.Errorf("attempt %d of workflow run %d doesn't exist. detail: %w", attempt, runID, err)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to get attempt %d of workflow run %d. detail: %w", attempt, runID, err)
+	}
+	return run.GetRunStartedAt().Time, nil
+}
+
+// completeWorkflowRuns fills the branch and the commit of workflow runs which artifacts were produced by.
+// The list API usually includes them, but some servers (e.g. older GitHub Enterprise Server) don't.
+// In that case it costs an API call per workflow run.
+func (d *Downloader) completeWorkflowRuns(ctx context.Context, owner, repo string, artifacts []*github.Artifact) error {
+	for _, a := range artifacts {
+		ref := a.GetWorkflowRun()
+		if ref == nil || ref.ID == nil || (ref.HeadBranch != nil && ref.HeadSHA != nil) {
+			continue
+		}
+		run, err := d.workflowRun(ctx, owner, repo, ref.GetID())
+		if err != nil {
+			return err
+		}
+		ref.HeadBranch = run.HeadBranch
+		ref.HeadSHA = run.HeadSHA
+	}
+	return nil
+}
+
+// sortByRunNumber sorts artifacts which satisfy the options by the run number of their workflow runs in Options.Order.
+// Only runs of those artifacts are resolved to limit API calls. The rest of artifacts follow them as they are.
+// Artifacts in the same run are sorted by the creation time.
+func (d *Downloader) sortByRunNumber(ctx context.Context, owner, repo string, artifacts []*github.Artifact, opts Options) ([]*github.Artifact, error) {
+	byCreated := opts
+	byCreated.SortBy = SORT_BY_CREATED
+	matched, err := FilterArtifacts(artifacts, byCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	runNumbers := map[int64]int{}
+	for _, a := range matched {
+		runID := a.GetWorkflowRun().GetID()
+		if _, ok := runNumbers[runID]; ok || runID == 0 {
+			continue
+		}
+		run, err := d.workflowRun(ctx, owner, repo, runID)
+		if err != nil {
+			return nil, err
+		}
+		runNumbers[runID] = run.GetRunNumber()
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := runNumbers[matched[i].GetWorkflowRun().GetID()], runNumbers[matched[j].GetWorkflowRun().GetID()]
+		if opts.Order == ORDER_ASC {
+			return a < b
+		}
+		return a > b
+	})
+
+	sorted := append([]*github.Artifact{}, matched...)
+	isMatched := map[*github.Artifact]bool{}
+	for _, a := range matched {
+		isMatched[a] = true
+	}
+	for _, a := range artifacts {
+		if !isMatched[a] {
+			sorted = append(sorted, a)
+		}
+	}
+	return sorted, nil
+}
+
+// workflowRunIDs returns IDs of runs of the workflow which the artifacts may be produced by.
+// workflow is either a file name (e.g. release.yml) or a numeric workflow ID.
+// Runs are listed from the newest within maxPages, and listing stops once all runs of the artifacts are found,
+// or runs get older than MAX_WORKFLOW_RUN_SPAN before the oldest artifact.
+func (d *Downloader) workflowRunIDs(ctx context.Context, owner, repo, workflow string, artifacts []*github.Artifact, maxPages int) (map[int64]bool, error) {
+	ids := map[int64]bool{}
+	wanted := map[int64]bool{}
+	var oldest time.Time
+	for _, a := range artifacts {
+		if id := a.GetWorkflowRun().GetID(); id != 0 {
+			wanted[id] = true
+		}
+		if created := a.GetCreatedAt().Time; oldest.IsZero() || created.Before(oldest) {
+			oldest = created
+		}
+	}
+	if len(wanted) == 0 {
+		return ids, nil
+	}
+	cutoff := oldest.Add(-MAX_WORKFLOW_RUN_SPAN)
+
+	list := func(opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+		return d.client.Actions.ListWorkflowRunsByFileName(ctx, owner, repo, workflow, opts)
+	}
+	if workflowID, err := strconv.ParseInt(workflow, 10, 64); err == nil {
+		list = func(opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+			return d.client.Actions.ListWorkflowRunsByID(ctx, owner, repo, workflowID, opts)
+		}
+	}
+
+	found := 0
+	err := d.paginate(ctx, fmt.Sprintf("runs of workflow %q", workflow), maxPages, func(opts *github.ListOptions) (*github.Response, bool, error) {
+		runs, resp, err := list(&github.ListWorkflowRunsOptions{ListOptions: *opts})
+		if err != nil {
+			return resp, false, err
+		}
+		stop := false
+		for _, run := range runs.WorkflowRuns {
+			if wanted[run.GetID()] && !ids[run.GetID()] {
+				found++
+			}
+			ids[run.GetID()] = true
+			// runs are listed from the newest
+			if run.GetCreatedAt().Time.Before(cutoff) {
+				stop = true
+			}
+		}
+		return resp, stop || found == len(wanted), nil
+	})
+	return ids, err
+}
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+func TestCandidatesByWorkflow(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/actions/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":3,"artifacts":[
+			{"id":3,"name":"c","created_at":"2024-01-03T00:00:00Z","workflow_run":{"id":30}},
+			{"id":2,"name":"b","created_at":"2024-01-02T00:00:00Z","workflow_run":{"id":20}},
+			{"id":1,"name":"a","created_at":"2024-01-01T00:00:00Z","workflow_run":{"id":10}}]}`)
+	})
+	mux.HandleFunc("/repos/o/r/actions/workflows/release.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":2,"workflow_runs":[{"id":30},{"id":10}]}`)
+	})
+	mux.HandleFunc("/repos/o/r/actions/workflows/42/runs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":1,"workflow_runs":[{"id":20}]}`)
+	})
+	d := newTestDownloader(t, mux)
+
+	for _, tt := range []struct {
+		workflow string
+		want     []int64
+	}{
+		{workflow: "release.yml", want: []int64{3, 1}},
+		{workflow: "42", want: []int64{2}},
+	} {
+		t.Run(tt.workflow, func(t *testing.T) {
+			artifacts, err := d.Candidates(context.Background(), "o", "r", Options{Workflow: tt.workflow})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := artifactIDs(artifacts); !slices.Equal(got, tt.want) {
+				t.Errorf("artifacts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowRunIDsStopsListing(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		artifacts []*github.Artifact
+		maxPages  int
+		wantPages []string
+	}{
+		{
+			name:      "all runs found",
+			artifacts: []*github.Artifact{{ID: github.Int64(1), WorkflowRun: &github.ArtifactWorkflowRun{ID: github.Int64(20)}, CreatedAt: &github.Timestamp{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}}},
+			wantPages: []string{"1", "2"},
+		},
+		{
+			// the run of the artifact isn't of the workflow
+			name:      "older than the artifacts",
+			artifacts: []*github.Artifact{{ID: github.Int64(1), WorkflowRun: &github.ArtifactWorkflowRun{ID: github.Int64(99)}, CreatedAt: &github.Timestamp{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}}},
+			wantPages: []string{"1", "2", "3"},
+		},
+		{
+			name:      "max pages",
+			artifacts: []*github.Artifact{{ID: github.Int64(1), WorkflowRun: &github.ArtifactWorkflowRun{ID: github.Int64(99)}, CreatedAt: &github.Timestamp{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}}},
+			maxPages:  1,
+			wantPages: []string{"1"},
+		},
+		{name: "no artifacts"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// a run per page from the newest
+			runs := []string{
+				`{"id":30,"created_at":"2024-03-01T00:00:00Z"}`,
+				`{"id":20,"created_at":"2024-02-01T00:00:00Z"}`,
+				`{"id":10,"created_at":"2023-12-01T00:00:00Z"}`,
+				`{"id":5,"created_at":"2023-06-01T00:00:00Z"}`,
+			}
+			var pages []string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/o/r/actions/workflows/release.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+				page := r.URL.Query().Get("page")
+				if page == "" {
+					page = "1"
+				}
+				pages = append(pages, page)
+				n, _ := strconv.Atoi(page)
+				if n < len(runs) {
+					w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, n+1))
+				}
+				fmt.Fprintf(w, `{"total_count":%d,"workflow_runs":[%s]}`, len(runs), runs[n-1])
+			})
+			d := newTestDownloader(t, mux)
+
+			if _, err := d.workflowRunIDs(context.Background(), "o", "r", "release.yml", tt.artifacts, tt.maxPages); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(pages, tt.wantPages) {
+				t.Errorf("listed pages %q, want %q", pages, tt.wantPages)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// ErrMissingScope is returned when the token obviously lacks a scope which is required to get artifacts.
+var ErrMissingScope = errors.New("the token lacks a required scope")
+
+// CheckScopes tells whether the token has the scopes required to read artifacts of the repository, or delete them if write is true.
+// Only a classic personal access token tells its scopes. The check is skipped for other tokens like fine-grained ones and GITHUB_TOKEN,
+// which fail later with 403 if they lack the permission.
+// It costs an API call, and another one to see whether the repository is public when the token lacks the repo scope.
+// ErrRepositoryNotFound is returned when the repository can't be seen then, since it may not exist as well as be private.
+func (d *Downloader) CheckScopes(ctx context.Context, owner, repo string, write bool) error {
+	var resp *github.Response
+	err := d.retry(ctx, "getting the authenticated user", func() error {
+		var err error
+		_, resp, err = d.client.Users.Get(ctx, "")
+		d.observeRate("getting the authenticated user", resp)
+		return withStatus(resp, err)
+	})
+	if err != nil {
+		// Tokens of GitHub Apps including GITHUB_TOKEN can't get the user.
+		if resp != nil && resp.StatusCode == http.StatusForbidden {
+			d.logger().Debug("skipped checking scopes of the token", "reason", err)
+			return nil
+		}
+		return fmt.Errorf("unable to get the authenticated user. detail: %w", err)
+	}
+	if _, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; !ok {
+		d.logger().Debug("skipped checking scopes of the token", "reason", "the token doesn't tell its scopes")
+		return nil
+	}
+	scopes := parseScopes(resp.Header.Get("X-OAuth-Scopes"))
+	d.logger().Debug("checking scopes of the token", "scopes", scopes)
+	if scopes["repo"] {
+		return nil
+	}
+
+	// Without the repo scope, only public repositories can be read.
+	var repository *github.Repository
+	err = d.retry(ctx, "getting repository", func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		repository, resp, err = d.client.Repositories.Get(ctx, owner, repo)
+		d.observeRate("getting repository", resp)
+		return withStatus(resp, err)
+	})
+	if isNotFound(err) {
+		// GitHub responds 404 rather than 403 for a private repository which the token can't see, so it may also be a typo.
+		return fmt.Errorf("%w. %s/%s not found, or private and needs the repo scope. check the owner and the repository for a typo, or add the scope to the token. scopes of the token: %q. detail: %w", ErrRepositoryNotFound, owner, repo, resp.Header.Get("X-OAuth-Scopes"), err)
+	}
+	if err == nil && repository.GetPrivate() {
+		return fmt.Errorf("%w. reading artifacts of the private repository %s/%s requires the repo scope. add it to the token. scopes of the token: %q", ErrMissingScope, owner, repo, resp.Header.Get("X-OAuth-Scopes"))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get repository. detail: %w", err)
+	}
+	if write && !scopes["public_repo"] {
+		return fmt.Errorf("%w. deleting artifacts of %s/%s requires the public_repo or repo scope. add either to the token. scopes of the token: %q", ErrMissingScope, owner, repo, resp.Header.Get("X-OAuth-Scopes"))
+	}
+	return nil
+}
+
+// parseScopes parses the X-OAuth-Scopes header, e.g. "repo, workflow".
+func parseScopes(header string) map[string]bool {
+	scopes := map[string]bool{}
+	for _, s := range strings.Split(header, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes[s] = true
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,59 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v55/github"
+)
+
+func TestCheckScopes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		// scopes is the X-OAuth-Scopes header. nil means none
+		scopes   *string
+		repo     string
+		write    bool
+		wantErr  error
+		wantRepo bool
+	}{
+		{name: "no header", repo: `{"private":true}`},
+		{name: "repo scope", scopes: github.String("repo, workflow"), repo: `{"private":true}`},
+		{name: "public", scopes: github.String(""), repo: `{"private":false}`, wantRepo: true},
+		{name: "private", scopes: github.String(""), repo: `{"private":true}`, wantErr: ErrMissingScope, wantRepo: true},
+		{name: "not found", scopes: github.String("public_repo"), wantErr: ErrRepositoryNotFound, wantRepo: true},
+		{name: "delete public", scopes: github.String(""), repo: `{"private":false}`, write: true, wantErr: ErrMissingScope, wantRepo: true},
+		{name: "delete public with public_repo", scopes: github.String("public_repo"), repo: `{"private":false}`, write: true, wantRepo: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRepo bool
+			mux := http.NewServeMux()
+			mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				if tt.scopes != nil {
+					w.Header().Set("X-OAuth-Scopes", *tt.scopes)
+				}
+				fmt.Fprint(w, `{"login":"u"}`)
+			})
+			mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+				gotRepo = true
+				if tt.repo == "" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, tt.repo)
+			})
+			d := newTestDownloader(t, mux)
+
+			err := d.CheckScopes(context.Background(), "o", "r", tt.write)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckScopes() = %v, want %v", err, tt.wantErr)
+			}
+			if gotRepo != tt.wantRepo {
+				t.Errorf("the repository is got: %v, want %v", gotRepo, tt.wantRepo)
+			}
+		})
+	}
+}
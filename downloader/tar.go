@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteTarGz repackages files in the zip archive into a gzip-compressed tar stream written to w.
+// Paths, modes, modification times and symlinks are kept. Files are narrowed down by Options.ExtractMatch and
+// Options.ExtractExclude, and directories are dropped then. Options.StripComponents, Options.Flatten and Options.Prefix
+// rename them as extraction does. Symlinks escaping the root of the tar and entries under symlinks are rejected
+// like extraction, or skipped with Options.SkipUnsafeSymlinks. Failures are returned as *ExtractError.
+func WriteTarGz(zipPath string, opts Options, w io.Writer) error {
+	return New(nil).writeTarGz(zipPath, opts, w)
+}
+
+func (d *Downloader) writeTarGz(zipPath string, opts Options, w io.Writer) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := d.writeTar(zipPath, opts, w); err != nil {
+		return &ExtractError{Err: err}
+	}
+	return nil
+}
+
+func (d *Downloader) writeTar(zipPath string, opts Options, w io.Writer) error {
+	zipfile, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("unable to open zip reader. detail: %w", err)
+	}
+	defer zipfile.Close()
+	if err := normalizeNames(zipfile.File); err != nil {
+		return fmt.Errorf("unable to repackage the artifact. detail: %w", err)
+	}
+	zipfile.File = stripComponents(zipfile.File, opts.StripComponents)
+	filtered := opts.ExtractMatch != "" || opts.ExtractExclude != ""
+	flat := newFlattener(opts.FlattenCollision)
+	// the tar may be extracted by a tool following them
+	links := symlinkNames(zipfile.File, opts)
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, file := range zipfile.File {
+		// the tar is extracted somewhere else, so the same entries as extraction are rejected
+		if _, err := resolveExtractPath(".", file.Name); err != nil {
+			return fmt.Errorf("unable to repackage the artifact. detail: %w", err)
+		}
+		name := strings.TrimSuffix(file.Name, "/")
+		if link := underSymlink(name, links); !opts.Flatten && link != "" {
+			err := fmt.Errorf("%w: %q is under symlink %q", errUnsafeSymlink, file.Name, link)
+			if opts.SkipUnsafeSymlinks {
+				d.logger().Warn("skipped an entry under a symlink", "name", file.Name, "error", err)
+				continue
+			}
+			return fmt.Errorf("unable to repackage the artifact. detail: %w", err)
+		}
+
+		if file.FileInfo().IsDir() {
+			if filtered || opts.Flatten {
+				continue
+			}
+			if err := writeTarEntry(tw, file, opts.prefixed(file.Name), ""); err != nil {
+				return err
+			}
+			continue
+		}
+		if !opts.shouldExtract(file.Name) {
+			continue
+		}
+
+		if opts.Flatten {
+			name, err = flat.rename(file.Name)
+			if err != nil {
+				return fmt.Errorf("unable to repackage the artifact. detail: %w", err)
+			}
+		}
+		name = opts.prefixed(name)
+
+		var target string
+		if file.Mode()&os.ModeSymlink != 0 {
+			target, err = readSymlink(file)
+			if err != nil {
+				return err
+			}
+			if err := checkSymlinkTarget(file.Name, target, ".", filepath.FromSlash(name)); err != nil {
+				if opts.SkipUnsafeSymlinks {
+					d.logger().Warn("skipped an unsafe symlink", "name", file.Name, "error", err)
+					continue
+				}
+				return fmt.Errorf("unable to repackage the artifact. detail: %w", err)
+			}
+		}
+		if err := writeTarEntry(tw, file, name, target); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("unable to write the tar. detail: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("unable to write the tar. detail: %w", err)
+	}
+	return nil
+}
+
+// writeTarEntry writes the zip entry into tw as the name with its metadata. link is the target of a symlink.
+func writeTarEntry(tw *tar.Writer, file *zip.File, name, link string) error {
+	header, err := tar.FileInfoHeader(file.FileInfo(), link)
+	if err != nil {
+		return fmt.Errorf("unable to make a tar header of %q. detail: %w", file.Name, err)
+	}
+	// FileInfoHeader takes only the base name
+	header.Name = name
+	header.ModTime = file.Modified
+	if header.Typeflag == tar.TypeDir {
+		// the same as extraction. An archive made on Windows has no executable bit
+		header.Mode = 0o755
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("unable to write the tar header of %q. detail: %w", file.Name, err)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open src file %q. detail: %w", file.Name, err)
+	}
+	defer src.Close()
+	if _, err := io.Copy(tw, src); err != nil {
+		return fmt.Errorf("unable to write %q into the tar. detail: %w", file.Name, err)
+	}
+	return nil
+}
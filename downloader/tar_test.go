@@ -0,0 +1,82 @@
+package downloader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// tarNames returns names of entries in the tar.gz stream, with the target of each symlink after "->".
+func tarNames(t *testing.T, b []byte) []string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeSymlink {
+			names = append(names, h.Name+" -> "+h.Linkname)
+			continue
+		}
+		names = append(names, h.Name)
+	}
+}
+
+func TestWriteTarGzRejectsUnsafeSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need a privilege on Windows")
+	}
+	link := fs.ModeSymlink | 0o777
+	zipPath := writeZip(t, []zipEntry{
+		{name: "run", body: "bin/run.sh", mode: link},
+		{name: "bin/run.sh", body: "run"},
+		{name: "up", body: "../etc", mode: link},
+		{name: "up/evil.txt", body: "evil"},
+		{name: "root", body: "/etc", mode: link},
+	})
+
+	var buf bytes.Buffer
+	if err := WriteTarGz(zipPath, Options{}, &buf); !errors.Is(err, errUnsafeSymlink) {
+		t.Errorf("WriteTarGz() = %v, want %v", err, errUnsafeSymlink)
+	}
+
+	buf.Reset()
+	if err := WriteTarGz(zipPath, Options{SkipUnsafeSymlinks: true}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tarNames(t, buf.Bytes()), []string{"run -> bin/run.sh", "bin/run.sh"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
+
+func TestWriteTarGzFlatten(t *testing.T) {
+	zipPath := writeZip(t, []zipEntry{
+		{name: "reports/"},
+		{name: "reports/linux/result.xml", body: "linux"},
+		{name: "reports/windows/result.xml", body: "windows"},
+	})
+
+	var buf bytes.Buffer
+	opts := Options{Flatten: true, FlattenCollision: FLATTEN_COLLISION_SUFFIX, Prefix: "out"}
+	if err := WriteTarGz(zipPath, opts, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tarNames(t, buf.Bytes()), []string{"out/result.xml", "out/result-1.xml"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
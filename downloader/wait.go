@@ -0,0 +1,36 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// POLL_INTERVAL is the interval of listing artifacts while waiting for them.
+const POLL_INTERVAL = 15 * time.Second
+
+// poll calls f until it returns anything other than ErrNoArtifacts or Options.WaitTimeout passes.
+// f is called only once if WaitTimeout is 0. The error of the timeout wraps context.DeadlineExceeded besides the last error of f.
+func (d *Downloader) poll(ctx context.Context, opts Options, f func(ctx context.Context) error) error {
+	if opts.WaitTimeout <= 0 {
+		return f(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.WaitTimeout)
+	defer cancel()
+
+	for {
+		err := f(ctx)
+		if !errors.Is(err, ErrNoArtifacts) {
+			return err
+		}
+		d.logger().Info("waiting for an artifact", "detail", err.Error(), "interval", POLL_INTERVAL)
+		timer := time.NewTimer(POLL_INTERVAL)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("timed out waiting for an artifact after %s. detail: %w: %w", opts.WaitTimeout, err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
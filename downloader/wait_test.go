@@ -0,0 +1,27 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPollTimesOut(t *testing.T) {
+	calls := 0
+	err := New(nil).poll(context.Background(), Options{WaitTimeout: 10 * time.Millisecond}, func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("o/r: %w", ErrNoArtifacts)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("poll() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// e.g. for Options.ReleaseFallback
+	if !errors.Is(err, ErrNoArtifacts) {
+		t.Errorf("poll() = %v, want %v as well", err, ErrNoArtifacts)
+	}
+	if calls != 1 {
+		t.Errorf("f is called %d times, want 1 within the interval", calls)
+	}
+}
@@ -0,0 +1,208 @@
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterFS is where files are extracted. See Options.FS.
+// Names are slash-separated paths relative to its root, which have been checked not to escape it.
+// Methods may be called concurrently by the workers of Options.ExtractConcurrency.
+type WriterFS interface {
+	// MkdirAll makes the directory with its parents like os.MkdirAll.
+	MkdirAll(name string, perm fs.FileMode) error
+	// Create creates or truncates the file. The content is written into the returned writer and closed.
+	Create(name string, perm fs.FileMode) (io.WriteCloser, error)
+}
+
+// DirFS returns the WriterFS writing into the directory on the OS, which is used for Options.OutputDir.
+func DirFS(dir string) WriterFS { return osFS{root: dir} }
+
+// osFS implements the optional methods below as well, to keep modification times and not to leave a truncated file.
+// Names are checked as text beforehand, but symlinks on the disk may still lead outside of the root, so it follows them before writing.
+type osFS struct {
+	root string
+}
+
+func (f osFS) path(name string) string { return filepath.Join(f.root, filepath.FromSlash(name)) }
+
+func (f osFS) MkdirAll(name string, perm fs.FileMode) error {
+	if err := f.within(name); err != nil {
+		return err
+	}
+	return os.MkdirAll(f.path(name), perm)
+}
+
+func (f osFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if err := f.within(path.Dir(name)); err != nil {
+		return nil, err
+	}
+	// a symlink in place would be written through, so it is replaced like tar does
+	p := f.path(name)
+	if info, err := os.Lstat(p); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		if err := os.Remove(p); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+// within fails with errUnsafeSymlink if the name is outside of the root in fact, following symlinks on the way.
+func (f osFS) within(name string) error {
+	root, err := realPath(f.root)
+	if err != nil {
+		return err
+	}
+	real, err := realPath(f.path(name))
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %q resolves to %q", errUnsafeSymlink, name, real)
+	}
+	return nil
+}
+
+// realPath resolves symlinks in the path. The rest which doesn't exist yet is joined as is.
+func realPath(p string) (string, error) {
+	real, err := filepath.EvalSymlinks(p)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return real, err
+	}
+	parent := filepath.Dir(p)
+	if parent == p {
+		return "", err
+	}
+	real, err = realPath(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(p)), nil
+}
+
+func (f osFS) Remove(name string) error { return os.Remove(f.path(name)) }
+
+func (f osFS) Chtimes(name string, mtime time.Time) error {
+	return os.Chtimes(f.path(name), mtime, mtime)
+}
+
+// prefixFS puts names under the directory of fsys, e.g. for each of multiple artifacts.
+type prefixFS struct {
+	fsys WriterFS
+	dir  string
+}
+
+func (f prefixFS) MkdirAll(name string, perm fs.FileMode) error {
+	return f.fsys.MkdirAll(path.Join(f.dir, name), perm)
+}
+
+func (f prefixFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	// a zip doesn't always have entries for parent directories, and neither does the prefix
+	if err := f.fsys.MkdirAll(f.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return f.fsys.Create(path.Join(f.dir, name), perm)
+}
+
+// MemFS is a WriterFS keeping files in memory, e.g. to extract an artifact in tests without touching the disk.
+// The zero value is an empty one.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string]*MemFile
+	dirs  map[string]bool
+}
+
+// MemFile is a file in MemFS.
+type MemFile struct {
+	Data []byte
+	Mode fs.FileMode
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dirs == nil {
+		m.dirs = map[string]bool{}
+	}
+	for dir := path.Clean(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if _, ok := m.files[dir]; ok {
+			return fmt.Errorf("mkdir %s: %q is a file", name, dir)
+		}
+		m.dirs[dir] = true
+	}
+	return nil
+}
+
+// Create returns the writer of the file, whose content is visible once it is closed.
+func (m *MemFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dirs[path.Clean(name)] {
+		return nil, fmt.Errorf("create %s: it is a directory", name)
+	}
+	return &memWriter{fsys: m, name: path.Clean(name), mode: perm}, nil
+}
+
+// ReadFile returns the content of the file.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return file.Data, nil
+}
+
+// Files returns the files by their names.
+func (m *MemFS) Files() map[string]*MemFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make(map[string]*MemFile, len(m.files))
+	for name, file := range m.files {
+		files[name] = file
+	}
+	return files
+}
+
+// Names returns the names of the files in lexical order.
+func (m *MemFS) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type memWriter struct {
+	fsys *MemFS
+	name string
+	mode fs.FileMode
+	buf  bytes.Buffer
+}
+
+func (w *memWriter) Write(b []byte) (int, error) { return w.buf.Write(b) }
+
+func (w *memWriter) Close() error {
+	w.fsys.mu.Lock()
+	defer w.fsys.mu.Unlock()
+	if w.fsys.files == nil {
+		w.fsys.files = map[string]*MemFile{}
+	}
+	w.fsys.files[w.name] = &MemFile{Data: w.buf.Bytes(), Mode: w.mode}
+	return nil
+}
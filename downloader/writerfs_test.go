@@ -0,0 +1,80 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExtractIntoMemFS(t *testing.T) {
+	zipPath := writeZip(t, []zipEntry{
+		{name: "docs/"},
+		{name: "docs/index.html", body: "<html>"},
+		{name: "bin/run.sh", body: "run", mode: 0o755},
+		{name: "latest", body: "docs", mode: fs.ModeSymlink | 0o777},
+	})
+	m := &MemFS{}
+	dir := filepath.Join(t.TempDir(), "out")
+
+	if err := New(nil).Extract(context.Background(), zipPath, Options{OutputDir: dir, FS: m, Prefix: "site"}); err != nil {
+		t.Fatal(err)
+	}
+	// symlinks can't be made in it
+	if got, want := m.Names(), []string{"site/bin/run.sh", "site/docs/index.html"}; !slices.Equal(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	if b, err := m.ReadFile("site/docs/index.html"); err != nil || string(b) != "<html>" {
+		t.Errorf("ReadFile() = %q, %v, want %q", b, err, "<html>")
+	}
+	if mode := m.Files()["site/bin/run.sh"].Mode; mode != 0o755 {
+		t.Errorf("mode = %v, want %v", mode, fs.FileMode(0o755))
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the output directory is made on the disk: %v", err)
+	}
+}
+
+func TestLatestArtifactsIntoMemFS(t *testing.T) {
+	archive, err := os.ReadFile(writeZip(t, []zipEntry{{name: "a.txt", body: "a"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/actions/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"total_count":2,"artifacts":[
+			{"id":2,"name":"linux","size_in_bytes":%[1]d,"created_at":"2024-01-02T00:00:00Z"},
+			{"id":1,"name":"windows","size_in_bytes":%[1]d,"created_at":"2024-01-01T00:00:00Z"}]}`, len(archive))
+	})
+	for _, id := range []string{"1", "2"} {
+		mux.HandleFunc("/repos/o/r/actions/artifacts/"+id+"/zip", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://"+r.Host+"/archive", http.StatusFound)
+		})
+	}
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { w.Write(archive) })
+	d := newTestDownloader(t, mux)
+	m := &MemFS{}
+	dir := filepath.Join(t.TempDir(), "out")
+
+	if _, err := d.LatestArtifacts(context.Background(), "o", "r", []string{"linux", "windows"}, Options{OutputDir: dir, FS: m, Stream: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Names(), []string{"linux/a.txt", "windows/a.txt"}; !slices.Equal(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the output directory is made on the disk: %v", err)
+	}
+}
+
+func TestMemFSRejectsManifest(t *testing.T) {
+	opts := Options{FS: &MemFS{}, Manifest: filepath.Join(t.TempDir(), "manifest.json")}
+	if err := opts.validate(); err == nil {
+		t.Error("validate() = nil, want an error for manifest with FS")
+	}
+}
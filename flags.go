@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/niku/get-the-latest-artifact-on-github-action/downloader"
+)
+
+// stringsFlag is a flag which can be repeated or given as a comma-separated list, e.g. "-flag a -flag b,c".
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
+
+// optionalStringFlag is a flag which can be given with or without a value, e.g. "-flag" and "-flag=value".
+type optionalStringFlag struct {
+	set   bool
+	value string
+}
+
+func (f *optionalStringFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *optionalStringFlag) Set(s string) error {
+	// "-flag" without a value is passed as "true" because of IsBoolFlag
+	switch s {
+	case "true":
+		f.set = true
+	case "false":
+		f.set = false
+	default:
+		f.set = true
+		f.value = s
+	}
+	return nil
+}
+
+// IsBoolFlag allows the flag to be given without a value.
+func (f *optionalStringFlag) IsBoolFlag() bool { return true }
+
+// timeFlag is a point in time given as either a RFC3339 timestamp or a duration ago (e.g. "168h").
+type timeFlag struct {
+	time.Time
+}
+
+func (f *timeFlag) String() string {
+	if f == nil || f.IsZero() {
+		return ""
+	}
+	return f.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(s string) error {
+	t, err := downloader.ParseTimeOrAgo(s)
+	if err != nil {
+		return err
+	}
+	f.Time = t
+	return nil
+}
+
+// filtersFlag is a flag of filters which can be repeated, e.g. "-filter size=gt:10M -filter name=glob:build-*".
+// It isn't split by commas unlike stringsFlag since a value may have them.
+type filtersFlag []downloader.Filter
+
+func (f *filtersFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var s []string
+	for _, filter := range *f {
+		s = append(s, filter.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func (f *filtersFlag) Set(s string) error {
+	filter, err := downloader.ParseFilter(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, filter)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// runPostExtract runs the shell command after the artifact has been extracted into dir.
+// The artifact is told by environment variables, and the output of the command is streamed as is.
+func runPostExtract(ctx context.Context, command string, info artifactInfo, dir string, files int) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"ARTIFACT_ID="+strconv.FormatInt(info.ID, 10),
+		"ARTIFACT_NAME="+info.Name,
+		"OUTPUT_DIR="+dir,
+		"EXTRACTED_COUNT="+strconv.Itoa(files),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("the post-extract-cmd failed. detail: %w", err)
+	}
+	return nil
+}
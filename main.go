@@ -1,25 +1,52 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"sort"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/google/go-github/v43/github"
+	"github.com/google/go-github/v55/github"
+	"github.com/niku/get-the-latest-artifact-on-github-action/downloader"
 	"golang.org/x/oauth2"
 )
 
 const (
 	VERSION    = "0.0.1"
 	REPOSITORY = "https://github.com/coop-sapporo/get-the-latest-artifact-on-github-action"
-	// https://docs.github.com/en/rest/guides/traversing-with-pagination#basics-of-pagination
-	MAX_NUMBER_PER_PAGE = 100
+	// Failures are categorized by exit codes so that CI scripts can branch on them.
+	EXIT_CODE_ERROR        = 1
+	EXIT_CODE_NO_ARTIFACTS = 2
+	EXIT_CODE_AUTH         = 3 // 401 or 403
+	EXIT_CODE_NETWORK      = 4 // including timeouts
+	EXIT_CODE_EXTRACTION   = 5
+	EXIT_CODE_NOT_FOUND    = 6 // the repository doesn't exist or can't be read
+	// It follows the convention of shells for SIGINT (128 + 2).
+	EXIT_CODE_INTERRUPTED = 130
+
+	DEFAULT_RETRIES = 3
+	// It is long enough to download an artifact of hundreds of MB.
+	DEFAULT_TIMEOUT = 10 * time.Minute
+	// It is a part of DEFAULT_TIMEOUT to leave time for the download.
+	DEFAULT_WAIT_TIMEOUT = 5 * time.Minute
+
+	LOG_FORMAT_TEXT = "text"
+	LOG_FORMAT_JSON = "json"
+
+	// modes selecting from -targets
+	MODE_EACH   = "each"
+	MODE_NEWEST = "newest"
 )
 
 // assume embedded by ldflags
@@ -29,112 +56,835 @@ var (
 )
 
 func main() {
+	// Signals cancel the context instead of killing the process, so that deferred cleanups like removing the temp file run.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+	if err != nil {
+		slog.Error(err.Error())
+		if interrupted {
+			os.Exit(EXIT_CODE_INTERRUPTED)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode returns the exit code for the category of the error.
+func exitCode(err error) int {
+	// the timeout of -wait wraps the last ErrNoArtifacts as well
+	if errors.Is(err, context.DeadlineExceeded) {
+		return EXIT_CODE_NETWORK
+	}
+	if errors.Is(err, downloader.ErrNoArtifacts) {
+		return EXIT_CODE_NO_ARTIFACTS
+	}
+	if errors.Is(err, downloader.ErrRepositoryNotFound) {
+		return EXIT_CODE_NOT_FOUND
+	}
+	if errors.Is(err, downloader.ErrMissingScope) {
+		return EXIT_CODE_AUTH
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && isAuthStatus(errResp.Response.StatusCode) {
+		return EXIT_CODE_AUTH
+	}
+	var statusErr interface{ HTTPStatus() int }
+	if errors.As(err, &statusErr) && isAuthStatus(statusErr.HTTPStatus()) {
+		return EXIT_CODE_AUTH
+	}
+	var extractErr *downloader.ExtractError
+	if errors.As(err, &extractErr) {
+		return EXIT_CODE_EXTRACTION
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return EXIT_CODE_NETWORK
+	}
+	return EXIT_CODE_ERROR
+}
+
+func isAuthStatus(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+func run(ctx context.Context) (err error) {
 	// Some cli tools(e.g. hub, gh) use GITHUB_TOKEN environment variable.
 	// We provide that the token can be used as a straight forward way.
 	githubToken := os.Getenv("GITHUB_TOKEN")
 	var (
-		owner string
-		repo  string
+		owner     string
+		repo      string
+		names     stringsFlag
+		nameGlob  string
+		excludes  stringsFlag
+		outputDir string
+		timeout   time.Duration
+		retries   int
+		verbose   bool
+
+		waitForRateLimit bool
+		baseURL          string
+		tokenFile        string
+		list             bool
+		format           string
+		dryRun           bool
+		includeExpired   bool
+		keepZip          optionalStringFlag
+		noExtract        bool
+		offset           int
+		branch           string
+		runID            int64
+		artifactID       int64
+		runAttempt       int
+		sha              string
+		workflow         string
+		maxPages         int
+		since            timeFlag
+		quiet            bool
+		archiveSHA256    string
+
+		skipUnsafeSymlinks bool
+		extractMatch       string
+		extractExclude     string
+		sortBy             string
+		order              string
+		version            bool
+		extractConcurrency int
+		overwrite          string
+		stdout             bool
+		printURL           bool
+		proxy              string
+		caCert             string
+		insecure           bool
+		wait               bool
+		waitTimeout        time.Duration
+		newerThan          timeFlag
+		tempDir            string
+		before             timeFlag
+		manifest           string
+		checksumFile       string
+		flatten            bool
+		flattenCollision   string
+		fallbackOnMissing  bool
+		asTar              string
+		minAge             time.Duration
+		app                githubApp
+		count              int
+		resume             bool
+		repoGlob           string
+		targets            stringsFlag
+		mode               string
+		deleteAfter        bool
+		skipScopeCheck     bool
+		postExtractCmd     string
+		printRunURL        bool
+		perPage            int
+		latestPerName      bool
+		retryBackoff       time.Duration
+		maxRetryBackoff    time.Duration
+		stateFile          string
+		color              string
+		stripComponents    int
+		filters            filtersFlag
+		prefix             string
+		configPath         string
+		releaseFallback    bool
+		ignoreCase         bool
+		stream             bool
+		streamMaxBytes     int64
+		maxSize            int64
+		failOnEmpty        bool
+		logLevel           slog.Level
+		logFormat          string
+		completion         string
+		noSummary          bool
 	)
-	flag.StringVar(&owner, "owner", "", "Repository owner")
-	flag.StringVar(&repo, "repo", "", "Repository")
+	// GitHub Actions sets GITHUB_REPOSITORY in the form of owner/repo.
+	var defaultOwner, defaultRepo string
+	if o, r, ok := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/"); ok {
+		defaultOwner, defaultRepo = o, r
+	}
+	flag.StringVar(&owner, "owner", defaultOwner, "Repository owner. Defaults to the owner in GITHUB_REPOSITORY")
+	flag.StringVar(&repo, "repo", defaultRepo, "Repository, or owner/repo which makes -owner optional. Defaults to the repository in GITHUB_REPOSITORY")
+	flag.Int64Var(&artifactID, "artifact-id", 0, "ID of the artifact to download. Listing artifacts is skipped, so options selecting artifacts are ignored")
+	flag.StringVar(&repoGlob, "repo-glob", "", "Repository name pattern instead of -repo. The latest artifact among matching repositories of the organization -owner is selected. It costs API calls for each repository")
+	flag.Var(&targets, "targets", "Repositories in the form of owner/repo (or repo of -owner) instead of -repo. Repeat it or give a comma-separated list. See -mode for how the artifact is selected")
+	flag.StringVar(&mode, "mode", MODE_EACH, "How to select from -targets. 'each' downloads the latest artifact of each repository into <output-dir>/<owner>/<repo>, 'newest' downloads the newest one among them")
+	flag.Var(&names, "name", "Artifact name (exact match). All artifacts are candidates if omitted. Repeat it or give a comma-separated list to download multiple artifacts into <output-dir>/<name>")
+	flag.StringVar(&nameGlob, "name-glob", "", "Artifact name pattern. '*', '?' and '[...]' are supported (see https://pkg.go.dev/path#Match)")
+	flag.BoolVar(&latestPerName, "latest-per-name", false, "Download the latest artifact of each distinct name into <output-dir>/<name>, without giving the names in advance")
+	flag.Var(&excludes, "exclude-name", "Artifact name or pattern to exclude. Repeat it or give a comma-separated list. It is applied after -name and -name-glob")
+	flag.BoolVar(&ignoreCase, "ignore-case", false, "Make -name, -name-glob and -exclude-name case-insensitive")
+	flag.Int64Var(&runID, "run-id", 0, "Workflow run ID. Only artifacts produced by the run are candidates")
+	flag.IntVar(&runAttempt, "run-attempt", 0, "Attempt of the workflow run of -run-id, e.g. 1 for the original run before re-runs. All attempts are candidates if omitted")
+	flag.StringVar(&workflow, "workflow", "", "Workflow file name (e.g. release.yml) or ID. Only artifacts produced by its runs are candidates")
+	flag.StringVar(&branch, "branch", "", "Branch which the workflow run producing the artifact ran on")
+	flag.StringVar(&sha, "sha", "", "Commit SHA (or its prefix) which the workflow run producing the artifact ran for")
+	flag.BoolVar(&includeExpired, "include-expired", false, "Make expired artifacts candidates. They are skipped by default")
+	flag.IntVar(&maxPages, "max-pages", 0, "Stop listing artifacts after the number of pages (see -per-page). 0 means no limit")
+	flag.IntVar(&perPage, "per-page", downloader.MAX_NUMBER_PER_PAGE, "Number of items per page of listings, clamped to 1..100. A smaller one exercises pagination, but costs more API calls")
+	flag.Var(&since, "since", "Ignore artifacts created before it, and stop listing artifacts once they are older than it. A RFC3339 timestamp or a duration ago (e.g. 168h)")
+	flag.StringVar(&sortBy, "sort-by", downloader.SORT_BY_CREATED, "Key to sort candidates. 'created', 'updated', 'size', 'name' or 'run-number'. The first one is selected")
+	flag.StringVar(&order, "order", downloader.ORDER_DESC, "Order to sort candidates. 'asc' or 'desc'")
+	flag.IntVar(&offset, "offset", 0, "Select the Nth newest artifact. 0 means the newest, 1 means the second newest")
+	flag.Var(&before, "before", "Ignore artifacts created at or after it, e.g. to get the latest artifact as of the time. A RFC3339 timestamp or a duration ago (e.g. 168h)")
+	flag.Var(&filters, "filter", "Predicate in the form of key=op:value which candidates must satisfy, e.g. size=gt:10M. Repeat it to require all of them. See README for keys and operators")
+	flag.DurationVar(&minAge, "min-age", 0, "Skip artifacts created within the duration (e.g. 30s), which may still be being uploaded")
+	flag.Var(&newerThan, "newer-than", "Only artifacts created after it are candidates. A RFC3339 timestamp or a duration ago (e.g. 1h)")
+	flag.BoolVar(&wait, "wait", false, "Wait until an artifact satisfying the conditions appears, polling every 15s. Artifacts are expected to be newer than the start unless -newer-than is given")
+	flag.DurationVar(&waitTimeout, "wait-timeout", DEFAULT_WAIT_TIMEOUT, "Time limit of -wait. It is also bounded by -timeout")
+	flag.IntVar(&count, "count", 1, "Number of the most recent artifacts to download. More than 1 extracts each of them into <output-dir>/<name>-<creation time>")
+	flag.StringVar(&outputDir, "output-dir", ".", "Directory which files in the artifact are extracted into")
+	flag.DurationVar(&timeout, "timeout", DEFAULT_TIMEOUT, "Time limit for the whole run including the download. 0 means no limit")
+	flag.IntVar(&retries, "retries", DEFAULT_RETRIES, "Number of retries for transient failures of API calls and the download")
+	flag.DurationVar(&retryBackoff, "retry-backoff", downloader.INITIAL_RETRY_BACKOFF, "Interval before the first retry. It is doubled on each retry, and each sleep is randomized between 0 and it")
+	flag.DurationVar(&maxRetryBackoff, "max-retry-backoff", downloader.MAX_RETRY_BACKOFF, "Cap of the interval between retries")
+	flag.BoolVar(&resume, "resume", false, "Resume an interrupted download of the archive from where it stopped, up to -retries times")
+	flag.BoolVar(&waitForRateLimit, "wait-for-rate-limit", false, "Wait until the API rate limit resets instead of failing (within -timeout)")
+	// GitHub Actions sets GITHUB_API_URL. It points to the instance where the workflow runs.
+	flag.StringVar(&baseURL, "base-url", os.Getenv("GITHUB_API_URL"), "Base URL of the GitHub API for GitHub Enterprise Server (e.g. https://github.example.com/api/v3). Defaults to GITHUB_API_URL")
+	flag.StringVar(&proxy, "proxy", "", "Proxy url (e.g. http://proxy.example.com:8080, socks5://127.0.0.1:1080). Defaults to HTTP_PROXY, HTTPS_PROXY and NO_PROXY")
+	flag.StringVar(&caCert, "ca-cert", "", "PEM file of CA certificates trusted in addition to the system ones (e.g. for a self-signed GitHub Enterprise Server)")
+	flag.BoolVar(&skipScopeCheck, "skip-scope-check", false, "Skip checking scopes of a classic personal access token before getting artifacts. It saves an API call")
+	flag.BoolVar(&insecure, "insecure", false, "Skip the verification of TLS certificates. It is for testing only")
+	flag.Int64Var(&app.appID, "app-id", 0, "ID of the GitHub App to authenticate as. It needs -installation-id and -private-key-file, and takes precedence over GITHUB_TOKEN")
+	flag.Int64Var(&app.installationID, "installation-id", 0, "ID of the installation of the GitHub App")
+	flag.StringVar(&app.privateKeyFile, "private-key-file", "", "PEM file of the private key of the GitHub App")
+	flag.StringVar(&tokenFile, "token-file", "", "File containing the GitHub token. '-' reads it from stdin. It takes precedence over GITHUB_TOKEN")
+	flag.BoolVar(&list, "list", false, "Print artifacts which satisfy the conditions instead of downloading")
+	flag.StringVar(&format, "format", FORMAT_TABLE, "Output format of -list. 'table' or 'json'")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the selected artifact and files which would be extracted, without extracting them")
+	flag.StringVar(&extractMatch, "extract-match", "", "Only extract files whose name in the archive matches the pattern (e.g. '*.pdf')")
+	flag.StringVar(&extractExclude, "extract-exclude", "", "Don't extract files whose name in the archive matches the pattern. It wins over -extract-match")
+	flag.StringVar(&overwrite, "overwrite", downloader.OVERWRITE_ALWAYS, "Policy for files which already exist. 'always' overwrites them, 'never' skips them, 'error' fails")
+	flag.StringVar(&prefix, "prefix", "", "Relative directory joined in front of every file name in the archive, e.g. to merge artifacts into the same -output-dir without collisions")
+	flag.IntVar(&stripComponents, "strip-components", 0, "Remove the number of leading directories from names in the archive like tar, e.g. 1 extracts myapp-1.2.3/bin/run as bin/run. Files without enough directories are skipped")
+	flag.BoolVar(&flatten, "flatten", false, "Extract all files into -output-dir itself by their base names, dropping directories in the archive")
+	flag.StringVar(&flattenCollision, "flatten-collision", downloader.FLATTEN_COLLISION_ERROR, "Policy for files with the same base name with -flatten. 'error' fails, 'suffix' numbers the later ones like report-1.pdf")
+	flag.IntVar(&extractConcurrency, "extract-concurrency", runtime.GOMAXPROCS(0), "Number of workers extracting files")
+	flag.BoolVar(&skipUnsafeSymlinks, "skip-unsafe-symlinks", false, "Skip symlinks pointing outside of -output-dir, and files under symlinks in the archive, with a warning instead of failing")
+	flag.BoolVar(&failOnEmpty, "fail-on-empty-extract", false, "Fail if the archive has no files to extract, or none of them satisfies -extract-match and -extract-exclude")
+	flag.Int64Var(&maxSize, "max-size", 0, "Refuse to download an archive larger than the bytes. 0 means no limit")
+	flag.StringVar(&archiveSHA256, "sha256", "", "Expected SHA-256 digest of the archive. Extraction is aborted on mismatch. -verbose prints the actual digest")
+	flag.Var(&keepZip, "keep-zip", "Keep the downloaded archive. '-keep-zip=path' saves it at the path instead of <output-dir>/<artifact name>.zip")
+	flag.StringVar(&checksumFile, "checksum-file", "", "Write SHA-256 digests of extracted files in the format of sha256sum after extraction. Paths in it are relative to -output-dir")
+	flag.StringVar(&manifest, "manifest", "", "Write a JSON file listing absolute paths and sizes of extracted files after extraction")
+	flag.BoolVar(&stream, "stream", false, "Extract the archive in memory without a temp file if it is not larger than -stream-max-bytes. It uses memory as large as the archive")
+	flag.Int64Var(&streamMaxBytes, "stream-max-bytes", downloader.DEFAULT_STREAM_MAX_BYTES, "Largest archive extracted in memory by -stream. Larger ones use a temp file")
+	flag.StringVar(&tempDir, "temp-dir", os.TempDir(), "Directory where the archive is temporarily saved. Point it to a large volume if the default one can't hold the archive")
+	flag.BoolVar(&noExtract, "no-extract", false, "Only save the archive at <output-dir>/<artifact name>.zip (or the path of -keep-zip=path) without extracting it")
+	flag.BoolVar(&stdout, "stdout", false, "Write the only file in the artifact to stdout instead of extracting it. Combine with -extract-match to narrow files down to one")
+	flag.StringVar(&asTar, "as-tar", "", "Write files in the artifact into the path as a tar.gz instead of extracting them. '-' writes it to stdout")
+	flag.BoolVar(&printURL, "print-url", false, "Print the download url of the archive to stdout instead of downloading it. It expires in a short time")
+	flag.BoolVar(&fallbackOnMissing, "fallback-on-missing", false, "Select the next artifact when the selected one has been deleted or expired before it is downloaded")
+	flag.BoolVar(&releaseFallback, "release-fallback", false, "Download an asset of the latest release matching -name or -name-glob when no artifacts are found")
+	flag.BoolVar(&printRunURL, "print-run-url", false, "Print the url of the workflow run which produced the selected artifact instead of downloading it. It costs an API call to get the run")
+	flag.StringVar(&stateFile, "state-file", "", "File recording the ID of the downloaded artifact. The download is skipped if the selected artifact is the same as the recorded one")
+	flag.StringVar(&postExtractCmd, "post-extract-cmd", "", "Shell command to run after the artifact is extracted. ARTIFACT_ID, ARTIFACT_NAME, OUTPUT_DIR and EXTRACTED_COUNT are set for it. The run fails if it exits non-zero")
+	flag.BoolVar(&deleteAfter, "delete-after-download", false, "Delete the artifact after it is downloaded and extracted successfully. The token needs the write permission of actions")
+	flag.BoolVar(&noSummary, "no-summary", false, "Don't append the summary of downloaded artifacts to the file of GITHUB_STEP_SUMMARY in GitHub Actions")
+	flag.BoolVar(&quiet, "quiet", false, "Don't print the selected artifact and the progress of the download. The progress is disabled anyway when stderr is not a terminal")
+	flag.BoolVar(&verbose, "verbose", false, "Print diagnostic messages to stderr. It is the same as -log-level debug")
+	flag.TextVar(&logLevel, "log-level", slog.LevelWarn, "Minimum level of messages printed to stderr. 'debug', 'info', 'warn' or 'error'")
+	flag.StringVar(&color, "color", COLOR_AUTO, "When to colorize messages. 'auto' colorizes them only for a terminal unless NO_COLOR is set, 'always' or 'never'")
+	flag.StringVar(&logFormat, "log-format", LOG_FORMAT_TEXT, "Format of messages printed to stderr. 'text' or 'json'")
+	flag.StringVar(&configPath, "config", "", "YAML file whose keys are names of flags (e.g. 'owner: niku'). Flags on the command line take precedence over it")
+	flag.BoolVar(&version, "version", false, "Print the code information and exit")
+	flag.StringVar(&completion, "completion", "", "Print the completion script for the shell and exit. 'bash', 'zsh' or 'fish'")
 	flag.Parse()
 
+	if completion != "" {
+		return writeCompletion(os.Stdout, completion, flag.CommandLine)
+	}
+
+	var fromConfig map[string]bool
+	if configPath != "" {
+		config, err := loadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		fromConfig, err = config.apply(flag.CommandLine)
+		if err != nil {
+			return err
+		}
+	}
+
+	if version {
+		printCodeInfo(os.Stdout)
+		return nil
+	}
+
+	if verbose {
+		logLevel = slog.LevelDebug
+	}
+	stderrColor, err := newPalette(color, os.Stderr)
+	if err != nil {
+		return err
+	}
+	stdoutColor, err := newPalette(color, os.Stdout)
+	if err != nil {
+		return err
+	}
+	var logWriter io.Writer = os.Stderr
+	// JSON lines are for machines
+	if stderrColor.enabled && logFormat == LOG_FORMAT_TEXT {
+		logWriter = colorWriter{w: os.Stderr, p: stderrColor}
+	}
+	logger, err := newLogger(logWriter, logFormat, logLevel)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	// flags set by the config are visited as well
+	onCommandLine := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		if !fromConfig[f.Name] {
+			onCommandLine[f.Name] = true
+		}
+	})
+	// the owner in the config gives way to the repo on the command line, which takes precedence
+	ownerSet := onCommandLine["owner"] || fromConfig["owner"] && !onCommandLine["repo"]
+	owner, repo, err = splitRepo(owner, repo, ownerSet)
+	if err != nil {
+		return err
+	}
+
 	requiredParameters := []string{owner, repo}
+	if repoGlob != "" {
+		requiredParameters = []string{owner}
+	}
+	var repositories []downloader.Repository
+	if len(targets) > 0 {
+		repositories, err = parseTargets(targets, owner)
+		if err != nil {
+			return err
+		}
+		requiredParameters = nil
+	}
+	if mode != MODE_EACH && mode != MODE_NEWEST {
+		return fmt.Errorf("invalid mode %q. it must be either %s or %s", mode, MODE_EACH, MODE_NEWEST)
+	}
 	for _, v := range requiredParameters {
 		if v == "" {
-			fmt.Fprintln(os.Stderr, "Parameters owner, repo are required")
 			flag.PrintDefaults()
 			fmt.Fprintln(os.Stderr, "")
-			printCodeInfo()
-			os.Exit(1)
+			printCodeInfo(os.Stderr)
+			return errors.New("parameters owner, repo are required unless GITHUB_REPOSITORY is set")
 		}
 	}
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: githubToken},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	githubClient := github.NewClient(tc)
-
-	// list artifacts
-	var artifacts []*github.Artifact
-	page := 1
-	for {
-		// NOTE: At this moment, we don't care about huge number of pages. we assume a couple or few pages.
-		artifactList, resp, err := githubClient.Actions.ListArtifacts(ctx, owner, repo, &github.ListOptions{PerPage: MAX_NUMBER_PER_PAGE, Page: page})
+	useApp, err := app.validate()
+	if err != nil {
+		return err
+	}
+	if useApp && tokenFile != "" {
+		return errors.New("token-file can't be used with the GitHub App")
+	}
+	if tokenFile != "" {
+		token, err := readToken(tokenFile)
 		if err != nil {
-			log.Fatalf("unable to list artifacts. pqge: %d, detail: %+v", page, err)
-		}
-		artifacts = append(artifacts, artifactList.Artifacts...)
-		page = resp.NextPage
-		// if there are no additional pages
-		if page == 0 {
-			break
+			return err
 		}
+		githubToken = token
 	}
 
-	// sort createdAt desc
-	sort.Slice(artifacts, func(i, j int) bool {
-		return artifacts[i].GetCreatedAt().After(artifacts[j].GetCreatedAt().Time)
-	})
+	// "now" is taken before anything else so that an artifact uploaded meanwhile isn't missed
+	if wait && newerThan.IsZero() {
+		newerThan.Time = time.Now()
+	}
+	if !wait {
+		waitTimeout = 0
+	}
 
-	// get the newest artifact
-	artifact := artifacts[0]
-	artifactID := artifact.GetID()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	// make a download url
-	url, _, err := githubClient.Actions.DownloadArtifact(ctx, owner, repo, artifactID, true)
+	transport, err := newTransport(transportOptions{proxy: proxy, caCert: caCert, insecure: insecure})
 	if err != nil {
-		log.Fatalf("unable to get download url. detail: %+v", err)
+		return err
 	}
+	if insecure {
+		slog.Warn("TLS certificates are NOT verified because of -insecure. Connections can be intercepted. Use it for testing only")
+	}
+	httpClient := &http.Client{Transport: transport}
 
-	// get an archive
-	resp, err := http.Get(url.String())
-	if err != nil {
-		log.Fatalf("unable to get artifact. detail: %+v", err)
+	if useApp {
+		token, err := app.installationToken(ctx, baseURL, httpClient)
+		if err != nil {
+			return err
+		}
+		githubToken = token
 	}
-	defer resp.Body.Close()
 
-	temp, err := os.CreateTemp("", "tmpfile-latest-pdf-*.zip")
+	// Public repositories can be read without a token.
+	// An empty token would be sent as is and fail, so no token is sent instead.
+	tc := httpClient
+	if githubToken != "" {
+		tc = authenticated(httpClient, githubToken)
+	} else {
+		logger.Debug("running unauthenticated since no token is given. only public repositories can be read with the lower rate limit")
+	}
+	githubClient, err := newGithubClient(baseURL, tc)
 	if err != nil {
-		log.Fatalf("unable to create temp file. detail: %+v", err)
+		return err
+	}
+
+	var name string
+	if len(names) == 1 {
+		name = names[0]
+	}
+	multiple := len(names) > 1
+	if multiple && (list || dryRun || printURL || stdout) {
+		return errors.New("multiple names can't be used with -list, -dry-run, -print-url and -stdout")
+	}
+	if count != 1 && (multiple || list || dryRun || printURL || stdout) {
+		return errors.New("count can't be used with multiple names, -list, -dry-run, -print-url and -stdout")
+	}
+	if repoGlob != "" && (multiple || count != 1 || list || dryRun || printURL || stdout) {
+		return errors.New("repo-glob can't be used with multiple names, -count, -list, -dry-run, -print-url and -stdout")
+	}
+	if len(targets) > 0 && (repoGlob != "" || multiple || count != 1 || list || dryRun || printURL || stdout) {
+		return errors.New("targets can't be used with -repo-glob, multiple names, -count, -list, -dry-run, -print-url and -stdout")
 	}
+	if artifactID != 0 && (len(targets) > 0 || repoGlob != "" || multiple || count != 1 || list || dryRun || stdout) {
+		return errors.New("artifact-id can't be used with -targets, -repo-glob, multiple names, -count, -list, -dry-run and -stdout")
+	}
+	if asTar != "" && (len(targets) > 0 || repoGlob != "" || artifactID != 0 || multiple || count != 1 || list || dryRun || printURL || stdout) {
+		return errors.New("as-tar can't be used with -targets, -repo-glob, -artifact-id, multiple names, -count, -list, -dry-run, -print-url and -stdout")
+	}
+
+	if latestPerName && (len(targets) > 0 || repoGlob != "" || artifactID != 0 || multiple || count != 1 || list || dryRun || printURL || printRunURL || stdout || asTar != "" || postExtractCmd != "") {
+		return errors.New("latest-per-name can't be used with -targets, -repo-glob, -artifact-id, multiple names, -count, -list, -dry-run, -print-url, -print-run-url, -stdout, -as-tar and -post-extract-cmd")
+	}
+	if printRunURL && (len(targets) > 0 || repoGlob != "" || multiple || count != 1 || list || dryRun || printURL || stdout || asTar != "" || postExtractCmd != "") {
+		return errors.New("print-run-url can't be used with -targets, -repo-glob, multiple names, -count, -list, -dry-run, -print-url, -stdout, -as-tar and -post-extract-cmd")
+	}
+	if stateFile != "" && (len(targets) > 0 || repoGlob != "" || artifactID != 0 || multiple || count != 1 || latestPerName || list || dryRun || printURL || printRunURL || stdout || asTar != "") {
+		return errors.New("state-file can't be used with -targets, -repo-glob, -artifact-id, multiple names, -count, -latest-per-name, -list, -dry-run, -print-url, -print-run-url, -stdout and -as-tar")
+	}
+	if postExtractCmd != "" && (len(targets) > 0 || multiple || count != 1 || list || dryRun || printURL || stdout || asTar != "" || noExtract || releaseFallback) {
+		// a release asset has no artifact to tell the command
+		return errors.New("post-extract-cmd can't be used with -targets, multiple names, -count, -list, -dry-run, -print-url, -stdout, -as-tar, -no-extract and -release-fallback")
+	}
+	// they don't select an artifact to wait for
+	if wait && (list || artifactID != 0) {
+		return errors.New("wait can't be used with -list and -artifact-id")
+	}
+
+	opts := downloader.Options{
+		Name:                name,
+		NameGlob:            nameGlob,
+		ExcludeNames:        excludes,
+		IgnoreCase:          ignoreCase,
+		RunID:               runID,
+		RunAttempt:          runAttempt,
+		Workflow:            workflow,
+		Branch:              branch,
+		SHA:                 sha,
+		IncludeExpired:      includeExpired,
+		MaxPages:            maxPages,
+		Since:               since.Time,
+		Before:              before.Time,
+		NewerThan:           newerThan.Time,
+		WaitTimeout:         waitTimeout,
+		Offset:              offset,
+		OutputDir:           outputDir,
+		ExtractMatch:        extractMatch,
+		ExtractExclude:      extractExclude,
+		Overwrite:           overwrite,
+		ExtractConcurrency:  extractConcurrency,
+		SkipUnsafeSymlinks:  skipUnsafeSymlinks,
+		ArchiveSHA256:       archiveSHA256,
+		MaxSize:             maxSize,
+		FailOnEmptyExtract:  failOnEmpty,
+		KeepZip:             keepZip.set,
+		NoExtract:           noExtract,
+		ZipPath:             keepZip.value,
+		TempDir:             tempDir,
+		Stream:              stream,
+		StreamMaxBytes:      streamMaxBytes,
+		Manifest:            manifest,
+		ChecksumFile:        checksumFile,
+		Flatten:             flatten,
+		StripComponents:     stripComponents,
+		Prefix:              prefix,
+		Filters:             filters,
+		FallbackOnMissing:   fallbackOnMissing,
+		MinAge:              minAge,
+		FlattenCollision:    flattenCollision,
+		DeleteAfterDownload: deleteAfter,
+		ReleaseFallback:     releaseFallback,
+	}
+	d := downloader.New(githubClient)
+	d.HTTPClient = httpClient
+	d.Retries = retries
+	d.RetryBackoff = retryBackoff
+	d.MaxRetryBackoff = maxRetryBackoff
+	d.WaitForRateLimit = waitForRateLimit
+	d.Resume = resume
+	// 0 is the default of the library, but it is clamped like the others here
+	d.PerPage = max(perPage, 1)
+	d.Logger = logger
+	summary := &stepSummary{}
+	d.OnFile = func(name string, size int64) {
+		logger.Debug("extracted a file", "name", name, "bytes", size)
+		summary.files++
+	}
+	// it is logged on failures as well, which are often caused by the rate limit
 	defer func() {
-		temp.Close()
-		os.RemoveAll(temp.Name())
+		if usage, ok := d.RateUsage(); ok {
+			logger.Debug("rate limit budget", "requests", usage.Requests, "consumed", usage.Consumed, "limit", usage.Last.Limit, "remaining", usage.Last.Remaining, "reset", usage.Last.Reset.Format(time.RFC3339))
+		}
 	}()
+	r := repository{serverURL: serverURL(baseURL), owner: owner, repo: repo}
+	if !quiet {
+		d.OnSelect = func(a *github.Artifact) {
+			printSelectedArtifact(os.Stderr, newArtifactInfo(a, r.ofArtifact(a)), stderrColor)
+		}
+	}
+	// A selected artifact may turn out to be missing with -fallback-on-missing, so only downloaded ones are summarized.
+	d.OnDownload = func(a *github.Artifact) {
+		summary.artifacts = append(summary.artifacts, newArtifactInfo(a, r.ofArtifact(a)))
+	}
+	// GitHub Actions sets it to the file whose content is shown in the page of the job.
+	// Modes which don't download anything aren't summarized.
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" && !noSummary && !list && !dryRun && !printURL && !printRunURL {
+		defer func() {
+			if err == nil {
+				err = summary.write(path)
+			}
+		}()
+	}
+	if !quiet {
+		d.OnDelete = func(a *github.Artifact) {
+			printDeletedArtifact(os.Stderr, a, stderrColor)
+		}
+	}
+	if !quiet && isTerminal(os.Stderr) {
+		progress := &progressPrinter{w: os.Stderr, p: stderrColor}
+		d.OnProgress = progress.report
+		defer progress.finish()
+	}
 
-	if _, err := io.Copy(temp, resp.Body); err != nil {
-		log.Fatalf("unable to copy response body to file. detail: %+v", err)
+	// only a single artifact has been extracted when it is called
+	postExtract := func(dir string) error {
+		if postExtractCmd == "" || len(summary.artifacts) == 0 {
+			return nil
+		}
+		return runPostExtract(ctx, postExtractCmd, summary.artifacts[len(summary.artifacts)-1], dir, summary.files)
 	}
-	temp.Close()
 
-	// unzip
-	zipfile, err := zip.OpenReader(temp.Name())
-	if err != nil {
-		log.Fatalf("unable to open zip reader. detail: %+v", err)
+	// A token lacking a scope would fail with a confusing 403 or 404 in the middle.
+	// Only the single repository is checked since others would cost API calls for each.
+	if githubToken != "" && !useApp && !skipScopeCheck && len(repositories) == 0 && repoGlob == "" {
+		if err := d.CheckScopes(ctx, owner, repo, deleteAfter); err != nil {
+			return err
+		}
 	}
-	defer zipfile.Close()
-	for _, file := range zipfile.File {
-		src, err := file.Open()
+
+	if len(repositories) > 0 {
+		if mode == MODE_NEWEST {
+			if _, _, err := d.LatestArtifactAmong(ctx, repositories, opts); err != nil {
+				return fmt.Errorf("unable to get the latest artifact. detail: %w", err)
+			}
+			return nil
+		}
+		if _, err := d.LatestArtifactEach(ctx, repositories, opts); err != nil {
+			return fmt.Errorf("unable to get the latest artifacts. detail: %w", err)
+		}
+		return nil
+	}
+
+	if repoGlob != "" {
+		repos, err := d.ListRepositories(ctx, owner, repoGlob)
+		if err != nil {
+			return err
+		}
+		if len(repos) == 0 {
+			return fmt.Errorf("no repositories matching %q in %s", repoGlob, owner)
+		}
+		_, dir, err := d.LatestArtifactAcross(ctx, owner, repos, opts)
+		if err != nil {
+			return fmt.Errorf("unable to get the latest artifact. detail: %w", err)
+		}
+		return postExtract(dir)
+	}
+
+	if artifactID != 0 {
+		if printRunURL {
+			artifact, err := d.GetArtifact(ctx, owner, repo, artifactID)
+			if err != nil {
+				return err
+			}
+			runURL, err := d.RunURL(ctx, owner, repo, artifact)
+			if err != nil {
+				return fmt.Errorf("unable to get the workflow run of artifact %d. detail: %w", artifactID, err)
+			}
+			fmt.Fprintln(os.Stdout, runURL)
+			return nil
+		}
+		if printURL {
+			// the artifact isn't even got, so it costs only an API call
+			downloadURL, err := d.DownloadURL(ctx, owner, repo, artifactID)
+			if err != nil {
+				return fmt.Errorf("unable to get the url of artifact %d. detail: %w", artifactID, err)
+			}
+			fmt.Fprintln(os.Stdout, downloadURL)
+			return nil
+		}
+		dir, err := d.ArtifactByID(ctx, owner, repo, artifactID, opts)
+		if err != nil {
+			return fmt.Errorf("unable to get artifact %d. detail: %w", artifactID, err)
+		}
+		return postExtract(dir)
+	}
+
+	if list {
+		artifacts, err := d.Candidates(ctx, owner, repo, opts)
+		if err != nil {
+			return err
+		}
+		return printArtifacts(os.Stdout, artifacts, format, r)
+	}
+
+	if dryRun {
+		result, err := d.DryRun(ctx, owner, repo, opts)
+		if err != nil {
+			return fmt.Errorf("unable to inspect the latest artifact. detail: %w", err)
+		}
+		printDryRunResult(os.Stdout, result, overwrite, stdoutColor)
+		return nil
+	}
+
+	if printRunURL {
+		runURL, err := d.LatestArtifactRunURL(ctx, owner, repo, opts)
 		if err != nil {
-			log.Fatalf("unable to open src file. detail: %+v", err)
+			return fmt.Errorf("unable to get the workflow run of the latest artifact. detail: %w", err)
 		}
-		defer src.Close()
+		fmt.Fprintln(os.Stdout, runURL)
+		return nil
+	}
+
+	if printURL {
+		downloadURL, err := d.LatestArtifactURL(ctx, owner, repo, opts)
+		if err != nil {
+			return fmt.Errorf("unable to get the url of the latest artifact. detail: %w", err)
+		}
+		fmt.Fprintln(os.Stdout, downloadURL)
+		return nil
+	}
+
+	if stdout {
+		if err := d.LatestArtifactTo(ctx, owner, repo, opts, os.Stdout); err != nil {
+			return fmt.Errorf("unable to get the latest artifact. detail: %w", err)
+		}
+		return nil
+	}
+
+	if asTar != "" {
+		if err := writeTar(ctx, d, owner, repo, opts, asTar); err != nil {
+			return fmt.Errorf("unable to get the latest artifact. detail: %w", err)
+		}
+		return nil
+	}
+
+	if count != 1 {
+		if _, err := d.RecentArtifacts(ctx, owner, repo, count, opts); err != nil {
+			return fmt.Errorf("unable to get the recent artifacts. detail: %w", err)
+		}
+		return nil
+	}
+
+	if latestPerName {
+		if _, err := d.LatestArtifactPerName(ctx, owner, repo, opts); err != nil {
+			return fmt.Errorf("unable to get the latest artifacts. detail: %w", err)
+		}
+		return nil
+	}
+
+	if multiple {
+		if _, err := d.LatestArtifacts(ctx, owner, repo, names, opts); err != nil {
+			return fmt.Errorf("unable to get the latest artifacts. detail: %w", err)
+		}
+		return nil
+	}
 
-		dst, err := os.Create(file.Name)
+	if stateFile != "" {
+		opts.LastID, err = readState(stateFile)
 		if err != nil {
-			log.Fatalf("unable to create dst file. detail: %+v", err)
+			return err
+		}
+	}
+	dir, err := d.LatestArtifact(ctx, owner, repo, opts)
+	if errors.Is(err, downloader.ErrUnchanged) {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "%s: artifact %d has been downloaded already\n", stderrColor.name("unchanged"), opts.LastID)
+		}
+		return writeOutput("unchanged", "true")
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get the latest artifact. detail: %w", err)
+	}
+	if err := postExtract(dir); err != nil {
+		return err
+	}
+	// a release asset isn't recorded since it isn't an artifact
+	if stateFile != "" && len(summary.artifacts) > 0 {
+		if err := writeState(stateFile, summary.artifacts[len(summary.artifacts)-1].ID); err != nil {
+			return err
 		}
-		defer dst.Close()
+		return writeOutput("unchanged", "false")
+	}
+	return nil
+}
+
+// writeTar writes the latest artifact as a tar.gz into the path, or stdout if it is "-".
+// The file is removed on failure not to leave a broken tarball.
+func writeTar(ctx context.Context, d *downloader.Downloader, owner, repo string, opts downloader.Options, path string) error {
+	if path == "-" {
+		return d.LatestArtifactAsTar(ctx, owner, repo, opts, os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create the tar. detail: %w", err)
+	}
+	if err := d.LatestArtifactAsTar(ctx, owner, repo, opts, f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("unable to write the tar. detail: %w", err)
+	}
+	return nil
+}
+
+// newLogger returns a logger writing messages at the level or above into w in the format.
+func newLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case LOG_FORMAT_TEXT:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LOG_FORMAT_JSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log-format %q. it must be %s or %s", format, LOG_FORMAT_TEXT, LOG_FORMAT_JSON)
+	}
+}
+
+// authenticated returns a client which sends the token with every request through the same transport as httpClient.
+// httpClient itself stays unauthenticated for urls which must not receive the token, like pre-signed urls of archives.
+func authenticated(httpClient *http.Client, token string) *http.Client {
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Base:   httpClient.Transport,
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+		},
+		Timeout: httpClient.Timeout,
+	}
+}
+
+// readToken reads a token from the file, or stdin if path is "-".
+func readToken(path string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read token-file %q. detail: %w", path, err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token-file %q is empty", path)
+	}
+	return token, nil
+}
+
+// serverURL returns the url of the web UI of the GitHub instance.
+func serverURL(baseURL string) string {
+	// GitHub Actions sets GITHUB_SERVER_URL. It points to the instance where the workflow runs.
+	if u := os.Getenv("GITHUB_SERVER_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	if baseURL == "" {
+		return "https://github.com"
+	}
+	// e.g. https://github.example.com/api/v3 -> https://github.example.com
+	return strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v3")
+}
+
+// newGithubClient returns a client for GitHub Enterprise Server when baseURL is given, otherwise for github.com.
+func newGithubClient(baseURL string, httpClient *http.Client) (*github.Client, error) {
+	if baseURL == "" {
+		return github.NewClient(httpClient), nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base-url %q. detail: %w", baseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base-url %q. it must be an absolute http(s) url", baseURL)
+	}
+	// The upload url isn't used, but it is derived to keep the client consistent.
+	// e.g. https://github.example.com/api/v3 -> https://github.example.com/api/uploads
+	uploadURL := strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v3") + "/api/uploads"
+	client, err := github.NewEnterpriseClient(baseURL, uploadURL, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base-url %q. detail: %w", baseURL, err)
+	}
+	return client, nil
+}
+
+// splitRepo splits repo in the form of owner/repo. The owner in it wins over the default one,
+// but it conflicts with the one given explicitly.
+func splitRepo(owner, repo string, ownerSet bool) (string, string, error) {
+	o, r, ok := strings.Cut(repo, "/")
+	if !ok {
+		return owner, repo, nil
+	}
+	if o == "" || r == "" || strings.Contains(r, "/") {
+		return "", "", fmt.Errorf("invalid repo %q. it must be either repo or owner/repo", repo)
+	}
+	if ownerSet && owner != o {
+		return "", "", fmt.Errorf("repo %q conflicts with owner %q", repo, owner)
+	}
+	return o, r, nil
+}
 
-		io.Copy(dst, src)
+// parseTargets parses targets in the form of owner/repo. The owner is used for a target without it.
+func parseTargets(targets []string, owner string) ([]downloader.Repository, error) {
+	seen := map[downloader.Repository]bool{}
+	var repos []downloader.Repository
+	for _, t := range targets {
+		o, r, err := splitRepo(owner, t, false)
+		if err != nil {
+			return nil, err
+		}
+		if o == "" || r == "" {
+			return nil, fmt.Errorf("invalid target %q. it must be owner/repo unless -owner is given", t)
+		}
+		repo := downloader.Repository{Owner: o, Name: r}
+		if seen[repo] {
+			continue
+		}
+		seen[repo] = true
+		repos = append(repos, repo)
 	}
+	return repos, nil
 }
 
-func printCodeInfo() {
+func printCodeInfo(w io.Writer) {
 	var t []string
 
 	t = append(t, "==== CODE INFOMATION ====")
@@ -155,6 +905,6 @@ func printCodeInfo() {
 	}
 
 	for _, line := range t {
-		fmt.Fprintln(os.Stderr, line)
+		fmt.Fprintln(w, line)
 	}
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/niku/get-the-latest-artifact-on-github-action/downloader"
+)
+
+const (
+	FORMAT_TABLE = "table"
+	FORMAT_JSON  = "json"
+)
+
+// repository locates the repository on the web.
+type repository struct {
+	serverURL string
+	owner     string
+	repo      string
+}
+
+// workflowRunURL returns the url of the workflow run page. It is empty if the run is unknown.
+func (r repository) workflowRunURL(runID int64) string {
+	if runID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s/actions/runs/%d", r.serverURL, r.owner, r.repo, runID)
+}
+
+// ofArtifact returns the repository which the artifact belongs to.
+// It is taken from the API url of the artifact, e.g. https://api.github.com/repos/owner/repo/actions/artifacts/1
+func (r repository) ofArtifact(a *github.Artifact) repository {
+	parts := strings.Split(a.GetURL(), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "repos" {
+			r.owner, r.repo = parts[i+1], parts[i+2]
+			break
+		}
+	}
+	return r
+}
+
+// artifactInfo is the metadata of an artifact. It is an element of the output of -format json.
+// Scripts may depend on the field names, so don't change them.
+type artifactInfo struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	SizeInBytes        int64  `json:"size_in_bytes"`
+	CreatedAt          string `json:"created_at"`
+	Expired            bool   `json:"expired"`
+	ArchiveDownloadURL string `json:"archive_download_url"`
+	WorkflowRunURL     string `json:"workflow_run_url,omitempty"`
+}
+
+func newArtifactInfo(a *github.Artifact, r repository) artifactInfo {
+	return artifactInfo{
+		ID:                 a.GetID(),
+		Name:               a.GetName(),
+		SizeInBytes:        a.GetSizeInBytes(),
+		CreatedAt:          a.GetCreatedAt().Format(time.RFC3339),
+		Expired:            a.GetExpired(),
+		ArchiveDownloadURL: a.GetArchiveDownloadURL(),
+		WorkflowRunURL:     r.workflowRunURL(a.GetWorkflowRun().GetID()),
+	}
+}
+
+// printSelectedArtifact prints the metadata of the artifact which is about to be downloaded.
+func printSelectedArtifact(w io.Writer, info artifactInfo, p palette) {
+	fmt.Fprintf(w, "selected artifact: id: %d, name: %s, size: %s, created_at: %s", info.ID, p.name(info.Name), p.size(fmt.Sprintf("%d bytes", info.SizeInBytes)), info.CreatedAt)
+	if info.WorkflowRunURL != "" {
+		fmt.Fprintf(w, ", workflow run: %s", info.WorkflowRunURL)
+	}
+	fmt.Fprintln(w)
+}
+
+// printDeletedArtifact prints the artifact deleted after the download.
+func printDeletedArtifact(w io.Writer, a *github.Artifact, p palette) {
+	fmt.Fprintf(w, "deleted artifact: id: %d, name: %s\n", a.GetID(), p.name(a.GetName()))
+}
+
+// printArtifacts prints artifacts in the format.
+func printArtifacts(w io.Writer, artifacts []*github.Artifact, format string, r repository) error {
+	switch format {
+	case FORMAT_TABLE:
+		return printArtifactsTable(w, artifacts)
+	case FORMAT_JSON:
+		return printArtifactsJSON(w, artifacts, r)
+	default:
+		return fmt.Errorf("unknown format %q. it must be %q or %q", format, FORMAT_TABLE, FORMAT_JSON)
+	}
+}
+
+func printArtifactsTable(w io.Writer, artifacts []*github.Artifact) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSIZE_IN_BYTES\tCREATED_AT\tEXPIRED")
+	for _, a := range artifacts {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%t\n", a.GetID(), a.GetName(), a.GetSizeInBytes(), a.GetCreatedAt().Format(time.RFC3339), a.GetExpired())
+	}
+	return tw.Flush()
+}
+
+func printArtifactsJSON(w io.Writer, artifacts []*github.Artifact, r repository) error {
+	// it is an empty array rather than null when there are no artifacts
+	out := make([]artifactInfo, 0, len(artifacts))
+	for _, a := range artifacts {
+		out = append(out, newArtifactInfo(a, r))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// printDryRunResult prints the selected artifact and files which would be extracted.
+// Existing ones are told according to the overwrite policy.
+func printDryRunResult(w io.Writer, result *downloader.DryRunResult, overwrite string, p palette) {
+	fmt.Fprintf(w, "artifact: %d %s\n", result.Artifact.GetID(), p.name(result.Artifact.GetName()))
+	for _, e := range result.Entries {
+		action := "would extract"
+		if e.Exists {
+			switch overwrite {
+			case downloader.OVERWRITE_NEVER:
+				action = "would skip existing"
+			case downloader.OVERWRITE_ERROR:
+				action = p.err("would fail on existing")
+			default:
+				action = p.warn("would overwrite")
+			}
+		}
+		fmt.Fprintf(w, "%s: %s\n", action, e.Path)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// It is frequent enough to look alive and rare enough not to flood.
+const PROGRESS_INTERVAL = 500 * time.Millisecond
+
+// progressPrinter prints the progress of the download on a line.
+type progressPrinter struct {
+	w       io.Writer
+	p       palette
+	last    time.Time
+	printed bool
+}
+
+func (p *progressPrinter) report(written, total int64) {
+	// the last report is always printed to show 100%
+	if time.Since(p.last) < PROGRESS_INTERVAL && written != total {
+		return
+	}
+	p.last = time.Now()
+	p.printed = true
+	if total > 0 {
+		fmt.Fprintf(p.w, "\rdownloading: %s / %s (%d%%)", p.p.size(formatBytes(written)), formatBytes(total), written*100/total)
+	} else {
+		fmt.Fprintf(p.w, "\rdownloading: %s", p.p.size(formatBytes(written)))
+	}
+}
+
+// finish ends the line of the progress.
+func (p *progressPrinter) finish() {
+	if p.printed {
+		fmt.Fprintln(p.w)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// isTerminal reports whether the file is a terminal.
+// Carriage returns for the progress only make sense there, not in logs.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
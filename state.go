@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readState returns the ID of the artifact recorded in the state file, or 0 if it doesn't exist yet.
+func readState(path string) (int64, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the state file. detail: %w", err)
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid state file %q. it must contain an artifact ID", path)
+	}
+	return id, nil
+}
+
+// writeState records the ID of the downloaded artifact into the state file.
+// It is renamed into place so that an interrupted write doesn't leave a broken state.
+func writeState(path string, id int64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write the state file. detail: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := fmt.Fprintln(tmp, id); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write the state file. detail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write the state file. detail: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to write the state file. detail: %w", err)
+	}
+	return nil
+}
+
+// writeOutput sets the output of the step of GitHub Actions if it runs there.
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-output-parameter
+func writeOutput(name, value string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to open the output of the step. detail: %w", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s=%s\n", name, value); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write the output of the step. detail: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write the output of the step. detail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stepSummary collects what has been downloaded for the step summary of GitHub Actions.
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#adding-a-job-summary
+type stepSummary struct {
+	artifacts []artifactInfo
+	files     int
+}
+
+// write appends the summary in markdown to the file. Nothing is written if no artifacts have been downloaded.
+func (s *stepSummary) write(path string) error {
+	if len(s.artifacts) == 0 {
+		return nil
+	}
+	// a pipe would break the table
+	escape := strings.NewReplacer("|", `\|`)
+	var b strings.Builder
+	b.WriteString("### Downloaded artifacts\n\n")
+	b.WriteString("| Name | Size | Workflow run |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, info := range s.artifacts {
+		run := "-"
+		if info.WorkflowRunURL != "" {
+			run = info.WorkflowRunURL
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", escape.Replace(info.Name), formatBytes(info.SizeInBytes), run)
+	}
+	fmt.Fprintf(&b, "\n%d files extracted.\n\n", s.files)
+
+	// Other steps and tools append to the same file.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to open the step summary. detail: %w", err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write the step summary. detail: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write the step summary. detail: %w", err)
+	}
+	return nil
+}
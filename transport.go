@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// transportOptions configures the transport.
+type transportOptions struct {
+	// proxy overrides HTTP_PROXY, HTTPS_PROXY and NO_PROXY if given.
+	proxy string
+	// caCert is a PEM bundle of certificates trusted in addition to the system ones.
+	caCert string
+	// insecure skips the verification of server certificates. It is for testing only.
+	insecure bool
+}
+
+// newTransport returns a transport shared by the API client and the archive download so that they behave consistently.
+func newTransport(opts transportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.proxy != "" {
+		u, err := url.Parse(opts.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q. detail: %w", opts.proxy, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid proxy %q. its scheme must be one of http, https, socks5, socks5h", opts.proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	if opts.caCert != "" || opts.insecure {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if opts.caCert != "" {
+		pool, err := loadCertPool(opts.caCert)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	if opts.insecure {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	return transport, nil
+}
+
+// loadCertPool returns the system cert pool with certificates in the PEM file.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ca-cert %q. detail: %w", path, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		// e.g. the system pool isn't available on the platform
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in ca-cert %q", path)
+	}
+	return pool, nil
+}